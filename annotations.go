@@ -0,0 +1,295 @@
+package sudoku
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// The markers of even/odd and greater-than puzzles, written over a board
+// rather than in it: cells shaded to hold even or odd values, and signs
+// between cells next to each other, each a marking with LessThan or
+// GreaterThan.
+type Annotations struct {
+    Even, Odd    []Pos
+    Inequalities []Marking
+}
+
+// Whether there are no markers at all.
+func (a Annotations) IsEmpty() bool {
+    return len(a.Even) == 0 && len(a.Odd) == 0 && len(a.Inequalities) == 0
+}
+
+// The annotations as layers over a board of the given size, as
+// ParseAnnotated reads them: one line of cells for the shading, with 'e'
+// and 'o' for even and odd cells, one for the signs between a cell and the
+// one to its right, with '<' and '>', and one for those between a cell and
+// the one below, with '^' where the top cell is the smaller and 'v' where
+// it is the larger. Layers with nothing on them are left out, and '.'
+// marks the cells a layer has nothing for.
+func (a Annotations) Layers(size int) []string {
+    blank := func() []rune {
+        return []rune(strings.Repeat(".", size * size))
+    }
+    shading, signs, below := blank(), blank(), blank()
+    for _, p := range a.Even {
+        shading[p.Row * size + p.Col] = 'e'
+    }
+    for _, p := range a.Odd {
+        shading[p.Row * size + p.Col] = 'o'
+    }
+    across, down := a.signs()
+    for p, sign := range across {
+        signs[p.Row * size + p.Col] = sign
+    }
+    for p, sign := range down {
+        below[p.Row * size + p.Col] = sign
+    }
+    layers := []string{}
+    for _, layer := range [][]rune{shading, signs, below} {
+        if text := string(layer); strings.Trim(text, ".") != "" {
+            layers = append(layers, text)
+        }
+    }
+    return layers
+}
+
+// The sign between each cell and the one to its right, and between each
+// and the one below, as Layers writes them.
+func (a Annotations) signs() (across, down map[Pos]rune) {
+    across, down = map[Pos]rune{}, map[Pos]rune{}
+    for _, m := range a.Inequalities {
+        p, q, less := m.Cells[0], m.Cells[1], m.Relation.Name == LessThan.Name
+        if q.Row < p.Row || q.Col < p.Col {
+            p, q, less = q, p, !less
+        }
+        switch {
+            case q.Row == p.Row && less:
+                across[p] = '<'
+            case q.Row == p.Row:
+                across[p] = '>'
+            case less:
+                down[p] = '^'
+            default:
+                down[p] = 'v'
+        }
+    }
+    return across, down
+}
+
+// The board as one line, as Format writes it, followed by the layers of
+// its annotations, each after a space.
+func (a Alphabet) FormatAnnotated(board Board, notes Annotations) string {
+    return strings.Join(append([]string{a.Format(board)}, notes.Layers(len(board))...), " ")
+}
+
+// Parse a board written on one line, followed by annotation layers as
+// Annotations.Layers writes them, separated by spaces or new lines. Any
+// number of layers may be given, so long as no two put markers of the
+// same kind on a cell. Input which isn't a one-line board and layers of
+// the same length, with a marker on at least one of them, is read as
+// Parse reads it, with no annotations.
+func ParseAnnotated(input string) (Board, Annotations, error) {
+    return Digits.ParseAnnotated(input)
+}
+
+// Parse a board written with the alphabet and annotation layers, as
+// ParseAnnotated does.
+func (a Alphabet) ParseAnnotated(input string) (Board, Annotations, error) {
+    type word struct {
+        text      []rune
+        line, col int
+    }
+    words := []word{}
+    for i, line := range strings.Split(input, "\n") {
+        for _, f := range fieldsOf(line, i + 1) {
+            words = append(words, word{[]rune(f.text), f.line, f.col})
+        }
+    }
+    // Each layer is a word as long as the board, of markers and dots, and
+    // some layer has to have a marker: otherwise rows of blanks in a grid
+    // would read as a smaller board with empty layers.
+    layered, marked := len(words) > 1, false
+    for i := 1; layered && i < len(words); i++ {
+        text := string(words[i].text)
+        layered = len(words[i].text) == len(words[0].text) && strings.Trim(text, ".eo<>^v") == ""
+        marked = marked || strings.Trim(text, ".") != ""
+    }
+    var board Board
+    if layered && marked {
+        board, _ = a.Parse(string(words[0].text))
+    }
+    if len(board) < 2 || len(board) * len(board) != len(words[0].text) {
+        board, err := a.Parse(input)
+        return board, Annotations{}, err
+    }
+
+    size := len(board)
+    notes := Annotations{}
+    shaded := map[Pos]bool{}
+    signs := map[[2]Pos]bool{}
+    for _, w := range words[1:] {
+        for i, r := range w.text {
+            p := Pos{i / size, i % size}
+            var q Pos
+            switch r {
+                case '.':
+                    continue
+                case 'e', 'o':
+                    if shaded[p] {
+                        return nil, Annotations{}, &ParseError{w.line, w.col + i, fmt.Sprintf("%v is shaded twice", p)}
+                    }
+                    shaded[p] = true
+                    if r == 'e' {
+                        notes.Even = append(notes.Even, p)
+                    } else {
+                        notes.Odd = append(notes.Odd, p)
+                    }
+                    continue
+                case '<', '>':
+                    q = Pos{p.Row, p.Col + 1}
+                default:
+                    q = Pos{p.Row + 1, p.Col}
+            }
+            if q.Row >= size || q.Col >= size {
+                return nil, Annotations{}, &ParseError{w.line, w.col + i, fmt.Sprintf("%q at %v points off the board", r, p)}
+            }
+            if signs[[2]Pos{p, q}] {
+                return nil, Annotations{}, &ParseError{w.line, w.col + i, fmt.Sprintf("there are two signs between %v and %v", p, q)}
+            }
+            signs[[2]Pos{p, q}] = true
+            relation := LessThan
+            if r == '>' || r == 'v' {
+                relation = GreaterThan
+            }
+            notes.Inequalities = append(notes.Inequalities, Marking{relation, [2]Pos{p, q}})
+        }
+    }
+    return board, notes, nil
+}
+
+// A copy of the geometry with the annotations' rules. Returns an error if
+// a marker leaves the board, a cell is shaded both even and odd, or a sign
+// isn't between cells next to each other.
+func (geo *Geometry) WithAnnotations(notes Annotations) (*Geometry, error) {
+    onBoard := func(p Pos) bool {
+        return p.Row >= 0 && p.Row < geo.Size && p.Col >= 0 && p.Col < geo.Size
+    }
+    s := &shading{even: map[Pos]bool{}}
+    for _, p := range notes.Even {
+        if !onBoard(p) {
+            return nil, fmt.Errorf("sudoku: the even cell %v is off the board", p)
+        }
+        s.even[p] = true
+        s.cells = append(s.cells, p)
+    }
+    for _, p := range notes.Odd {
+        if !onBoard(p) {
+            return nil, fmt.Errorf("sudoku: the odd cell %v is off the board", p)
+        }
+        if s.even[p] {
+            return nil, fmt.Errorf("sudoku: %v is shaded both even and odd", p)
+        }
+        s.cells = append(s.cells, p)
+    }
+    for i, m := range notes.Inequalities {
+        p, q := m.Cells[0], m.Cells[1]
+        if m.Relation.Name != LessThan.Name && m.Relation.Name != GreaterThan.Name {
+            return nil, fmt.Errorf("sudoku: inequality %d is a %s", i + 1, strings.ToLower(m.Relation.Name))
+        }
+        if d := (p.Row - q.Row) * (p.Row - q.Row) + (p.Col - q.Col) * (p.Col - q.Col); d != 1 {
+            return nil, fmt.Errorf("sudoku: inequality %d is between %v and %v, which aren't next to each other", i + 1, p, q)
+        }
+    }
+    geo, err := geo.WithMarkings(notes.Inequalities...)
+    if err != nil || len(s.cells) == 0 {
+        return geo, err
+    }
+    sort.Slice(s.cells, func(i, j int) bool {
+        return s.cells[i].Row < s.cells[j].Row || s.cells[i].Row == s.cells[j].Row && s.cells[i].Col < s.cells[j].Col
+    })
+    return geo.with(s), nil
+}
+
+// The annotations the geometry's constraints came from, for drawing them.
+func (geo *Geometry) annotations() Annotations {
+    notes := Annotations{}
+    if geo == nil {
+        return notes
+    }
+    for _, c := range geo.Constraints {
+        switch c := c.(type) {
+            case *shading:
+                for _, p := range c.cells {
+                    if c.even[p] {
+                        notes.Even = append(notes.Even, p)
+                    } else {
+                        notes.Odd = append(notes.Odd, p)
+                    }
+                }
+            case *related:
+                if c.relation.Name == LessThan.Name || c.relation.Name == GreaterThan.Name {
+                    for _, pair := range c.pairs {
+                        notes.Inequalities = append(notes.Inequalities, Marking{c.relation, pair})
+                    }
+                }
+        }
+    }
+    return notes
+}
+
+// Shaded cells can only hold values of their parity. The cells are in
+// reading order; those not in even are odd.
+type shading struct {
+    cells []Pos
+    even  map[Pos]bool
+}
+
+func (*shading) Name() string {
+    return "Even/odd"
+}
+
+// Easier than any technique: it only needs the shading.
+func (*shading) Difficulty() float64 {
+    return 1.0
+}
+
+func (s *shading) Apply(g *Grid) Result {
+    r := Result{}
+    var evens, odds Cell
+    for v := 1; v <= g.values; v++ {
+        if v % 2 == 0 {
+            evens |= C(v)
+        } else {
+            odds |= C(v)
+        }
+    }
+    for _, even := range []bool{true, false} {
+        d := Deduction{Technique: s.Name(), Values: evens}
+        if !even {
+            d.Values = odds
+        }
+        for _, p := range s.cells {
+            if s.even[p] == even {
+                d.Cells = append(d.Cells, p)
+                d.Eliminations = append(d.Eliminations, Elimination{p, g.At(p).difference(d.Values)})
+            }
+        }
+        r.add(g, d)
+    }
+    return r
+}
+
+// A shaded cell breaks the rule once it is solved with the wrong parity.
+func (s *shading) Check(g *Grid) error {
+    for i, p := range s.cells {
+        if cell := g.At(p); cell.IsSolved() && (cell.Value() % 2 == 0) != s.even[p] {
+            parity := "odd"
+            if s.even[p] {
+                parity = "even"
+            }
+            return &ContradictionError{ShadedUnit, i, cell.Value(), fmt.Sprintf("%v is shaded %s, but is %d", p, parity, cell.Value())}
+        }
+    }
+    return nil
+}
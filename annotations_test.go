@@ -0,0 +1,127 @@
+package sudoku
+
+import (
+    "context"
+    "errors"
+    "io/ioutil"
+    "strings"
+    "testing"
+)
+
+// A 4x4 board with its shading, the signs to the right of cells and the
+// signs below them.
+const annotated = "1..4.......2.... ..e.o........... <.>..<.......... ^....v.........."
+
+func TestAnnotationsRoundTrip(t *testing.T) {
+    board, notes, err := ParseAnnotated(annotated)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if len(notes.Even) != 1 || len(notes.Odd) != 1 || len(notes.Inequalities) != 5 {
+        t.Errorf("Parsed the wrong annotations: %v", notes)
+    }
+    if m := notes.Inequalities[1]; m.Relation.Name != GreaterThan.Name || m.Cells != [2]Pos{{0, 2}, {0, 3}} {
+        t.Errorf("Expected r1c3 to be greater than r1c4, but got %v", m)
+    }
+    if out := Digits.FormatAnnotated(board, notes); out != annotated {
+        t.Errorf("Expected %q, but got %q", annotated, out)
+    }
+}
+
+func TestParseAnnotatedReadsPlainBoards(t *testing.T) {
+    text, err := ioutil.ReadFile("solved.txt")
+    if err != nil {
+        t.Fatalf("Could not read the board: %v", err)
+    }
+    board, notes, err := ParseAnnotated(string(text))
+    if err != nil || !notes.IsEmpty() {
+        t.Fatalf("Expected a plain board, but got %v and %v", notes, err)
+    }
+    if same, msg := board.Equals(solved); !same {
+        t.Errorf("Unexpected board: %v", msg)
+    }
+}
+
+func TestParseAnnotatedReadsBlankGrids(t *testing.T) {
+    spaced := func(row string, n int) string {
+        return strings.TrimSpace(strings.Repeat(row + "\n", n))
+    }
+    for input, size := range map[string]int{
+        spaced("....", 4): 4,
+        spaced(". . . .", 4): 4,
+        spaced(".........", 9): 9,
+        spaced(". . . | . . . | . . .", 9): 9,
+    } {
+        board, notes, err := ParseAnnotated(input)
+        if err != nil || len(board) != size || !notes.IsEmpty() {
+            t.Errorf("Expected a blank %dx%d board for\n%s\nbut got %v, %v and %v", size, size, input, board, notes, err)
+        }
+    }
+}
+
+func TestParseAnnotatedSaysWhereTheInputIsWrong(t *testing.T) {
+    for input, expected := range map[string]string{
+        "1..4.......2.... e............... o...............": "sudoku: line 1, column 35: r1c1 is shaded twice",
+        "1..4.......2.... ...>............": "sudoku: line 1, column 21: '>' at r1c4 points off the board",
+        "1..4.......2.... ............^...": "sudoku: line 1, column 30: '^' at r4c1 points off the board",
+        "1..4.......2.... <...............\n>...............": "sudoku: line 2, column 1: there are two signs between r1c1 and r1c2",
+    } {
+        _, _, err := ParseAnnotated(input)
+        if err == nil || err.Error() != expected {
+            t.Errorf("For %q expected %q, but got %v", input, expected, err)
+        }
+    }
+}
+
+func TestWithAnnotationsRejectsBadMarkers(t *testing.T) {
+    for _, notes := range []Annotations{
+        {Even: []Pos{{0, 9}}},
+        {Even: []Pos{{0, 0}}, Odd: []Pos{{0, 0}}},
+        {Inequalities: []Marking{{LessThan, [2]Pos{{0, 0}, {1, 1}}}}},
+        {Inequalities: []Marking{{WhiteDot, [2]Pos{{0, 0}, {0, 1}}}}},
+    } {
+        if _, err := StandardGeometry(9).WithAnnotations(notes); err == nil {
+            t.Errorf("Expected an error for %v", notes)
+        }
+    }
+}
+
+func TestShadingAndSignsPrune(t *testing.T) {
+    geo, _ := StandardGeometry(9).WithAnnotations(Annotations{
+        Odd: []Pos{{0, 2}},
+        Inequalities: []Marking{{LessThan, [2]Pos{{0, 0}, {0, 1}}}, {GreaterThan, [2]Pos{{0, 2}, {0, 1}}}},
+    })
+    g, _ := geo.NewGrid(NewBoard(9))
+    if err := geo.solver().Propagate(context.Background(), g); err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    // The bounds carry along the chain r1c1 < r1c2 < r1c3.
+    for i, expected := range []Cell{C(1, 2, 3, 4, 5, 6, 7), C(2, 3, 4, 5, 6, 7, 8), C(3, 5, 7, 9)} {
+        if cell := g.At(Pos{0, i}); cell != expected {
+            t.Errorf("Expected r1c%d to be left with %v, but got %v", i + 1, expected, cell)
+        }
+    }
+}
+
+func TestReportsContradictionsInAnnotations(t *testing.T) {
+    geo, _ := StandardGeometry(9).WithAnnotations(Annotations{Even: []Pos{{0, 2}}, Odd: []Pos{{0, 0}}})
+    board := NewBoard(9)
+    board[0][2] = C(3)
+    _, err := geo.NewGrid(board)
+    var contradiction *ContradictionError
+    if !errors.As(err, &contradiction) || contradiction.Unit != ShadedUnit || contradiction.Index != 1 || !strings.Contains(err.Error(), "shaded cell 2: r1c3 is shaded even, but is 3") {
+        t.Errorf("Expected a contradiction in the second shaded cell, but got %v", err)
+    }
+}
+
+func TestDrawShowsAnnotations(t *testing.T) {
+    board, notes, _ := ParseAnnotated(annotated)
+    geo, err := StandardGeometry(4).WithAnnotations(notes)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    expected := " 1<  |e > 4|\n ^\no    <     |\n----v-------\n"
+    if out := geo.Draw(board); !strings.HasPrefix(out, expected) {
+        t.Errorf("Expected it to start\n%s\nbut got\n%s", expected, out)
+    }
+}
@@ -0,0 +1,199 @@
+package sudoku
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "io"
+    "runtime"
+    "strings"
+    "sync"
+    "time"
+)
+
+// How SolveAll works through a batch.
+type Options struct {
+    // How many puzzles are solved at once; 0 means one per CPU.
+    Workers int
+    // Stop counting a puzzle's solutions once this many are found; 0 means
+    // 2, which is enough to tell whether it is unique.
+    Limit int
+    // Give up on a puzzle after this long; 0 means never.
+    Timeout time.Duration
+}
+
+// How one puzzle of a batch went.
+type Outcome struct {
+    // Where the puzzle was in the input, counting from 1.
+    Line int
+    Input string
+    // The first solution found, or nil.
+    Solution Board
+    // How many solutions were found, up to Options.Limit.
+    Solutions int
+    Elapsed time.Duration
+    // Why there is no solution, if there isn't.
+    Err error
+}
+
+// The outcome as SolveAll writes it: the solution (or the input, if there
+// is none), the number of solutions and the time taken, separated by tabs,
+// then the error if there was one.
+func (o Outcome) String() string {
+    board := o.Input
+    if o.Solution != nil {
+        board = o.Solution.String()
+    }
+    line := fmt.Sprintf("%s\t%d\t%v", board, o.Solutions, o.Elapsed)
+    if o.Err != nil {
+        line += "\t" + o.Err.Error()
+    }
+    return line
+}
+
+// Totals for a batch.
+type Summary struct {
+    Puzzles int
+    // Puzzles which couldn't be parsed or solved.
+    Failed int
+    // Puzzles with more than one solution.
+    Multiple int
+    // The time spent solving, summed over every puzzle.
+    Elapsed time.Duration
+}
+
+func (s *Summary) add(o Outcome) {
+    s.Puzzles++
+    s.Elapsed += o.Elapsed
+    if o.Err != nil {
+        s.Failed++
+    } else if o.Solutions > 1 {
+        s.Multiple++
+    }
+}
+
+// Solve every puzzle read from in, one per line in the format Parse reads,
+// writing an Outcome per line to out in the same order. Blank lines and
+// lines starting with '#' are skipped. Puzzles are solved by a pool of
+// goroutines; a puzzle which fails doesn't stop the rest. Returns an error
+// only when reading, writing or the context fails.
+func SolveAll(ctx context.Context, in io.Reader, out io.Writer, opts Options) (Summary, error) {
+    workers := opts.Workers
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    type job struct {
+        index, line int
+        text string
+    }
+    type result struct {
+        index int
+        Outcome
+    }
+    jobs := make(chan job)
+    results := make(chan result)
+    // Keeps the reader from getting too far ahead of a slow puzzle.
+    window := make(chan struct{}, 4 * workers)
+
+    readErr := make(chan error, 1)
+    go func() {
+        defer close(jobs)
+        scanner := bufio.NewScanner(in)
+        index, line := 0, 0
+        for scanner.Scan() {
+            line++
+            text := strings.TrimSpace(scanner.Text())
+            if text == "" || text[0] == '#' {
+                continue
+            }
+            select {
+                case window <- struct{}{}:
+                case <-ctx.Done():
+                    readErr <- ctx.Err()
+                    return
+            }
+            jobs <- job{index, line, text}
+            index++
+        }
+        readErr <- scanner.Err()
+    }()
+
+    var wg sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := range jobs {
+                results <- result{j.index, opts.solve(ctx, j.line, j.text)}
+            }
+        }()
+    }
+    go func() {
+        wg.Wait()
+        close(results)
+    }()
+
+    var summary Summary
+    var err error
+    pending := map[int]Outcome{}
+    next := 0
+    for r := range results {
+        pending[r.index] = r.Outcome
+        for o, ok := pending[next]; ok; o, ok = pending[next] {
+            delete(pending, next)
+            next++
+            <-window
+            if err != nil || ctx.Err() != nil {
+                continue
+            }
+            summary.add(o)
+            if _, err = fmt.Fprintln(out, o); err != nil {
+                cancel()
+            }
+        }
+    }
+    if err != nil {
+        return summary, err
+    }
+    if err = <-readErr; err != nil {
+        return summary, err
+    }
+    return summary, ctx.Err()
+}
+
+func (opts Options) solve(ctx context.Context, line int, text string) (o Outcome) {
+    o = Outcome{Line: line, Input: text}
+    start := time.Now()
+    defer func() {
+        o.Elapsed = time.Since(start)
+    }()
+
+    board, err := Parse(text)
+    if err != nil {
+        o.Err = err
+        return o
+    }
+    if opts.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+        defer cancel()
+    }
+    limit := opts.Limit
+    if limit <= 0 {
+        limit = 2
+    }
+    o.Err = defaultSolver.each(ctx, board, nil, func(b Board) bool {
+        if o.Solution == nil {
+            o.Solution = b
+        }
+        o.Solutions++
+        return o.Solutions < limit
+    })
+    if o.Err == nil && o.Solutions == 0 {
+        o.Err = ErrNoProgress
+    }
+    return o
+}
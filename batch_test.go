@@ -0,0 +1,105 @@
+package sudoku
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestSolveAllWritesSolutionsInInputOrder(t *testing.T) {
+    puzzles := []string{}
+    for seed := int64(0); seed < 20; seed++ {
+        board, err := Generate(GenerateOptions{Seed: seed})
+        if err != nil {
+            t.Fatalf("Unexpected error %v", err)
+        }
+        puzzles = append(puzzles, board.String())
+    }
+
+    var out bytes.Buffer
+    summary, err := SolveAll(context.Background(), strings.NewReader(strings.Join(puzzles, "\n")), &out, Options{Workers: 4})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if summary.Puzzles != 20 || summary.Failed != 0 || summary.Multiple != 0 {
+        t.Errorf("Unexpected summary %+v", summary)
+    }
+
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    if len(lines) != len(puzzles) {
+        t.Fatalf("Expected %d lines, but got %d", len(puzzles), len(lines))
+    }
+    for i, line := range lines {
+        fields := strings.Split(line, "\t")
+        solution, err := Parse(fields[0])
+        if err != nil || !solution.IsSolved() || fields[1] != "1" {
+            t.Errorf("Line %d is not a unique solution: %v", i + 1, line)
+            continue
+        }
+        for j, c := range puzzles[i] {
+            if c != '.' && byte(c) != fields[0][j] {
+                t.Errorf("Line %d doesn't solve puzzle %d: %v", i + 1, i + 1, line)
+                break
+            }
+        }
+    }
+}
+
+func TestSolveAllReportsFailuresAndCounts(t *testing.T) {
+    input := "# a comment\n" +
+        "1234341221434...\n" +
+        "\n" +
+        "12x4\n" +
+        "1..1............\n" +
+        "................\n"
+
+    var out bytes.Buffer
+    summary, err := SolveAll(context.Background(), strings.NewReader(input), &out, Options{Workers: 2, Limit: 3})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if summary.Puzzles != 4 || summary.Failed != 2 || summary.Multiple != 1 {
+        t.Errorf("Unexpected summary %+v", summary)
+    }
+
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    expected := []string{"1234341221434321\t1\t", "\tsudoku: line 1, column 3: unexpected 'x'", "\tsudoku: contradiction", "\t3\t"}
+    for i := range expected {
+        if i >= len(lines) || !strings.Contains(lines[i], expected[i]) {
+            t.Errorf("Expected line %d to contain %q, but got %v", i + 1, expected[i], lines)
+        }
+    }
+}
+
+func TestSolveAllStopsWhenTheContextIsDone(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    input := strings.Repeat(strings.Repeat(".", 81) + "\n", 100)
+    _, err := SolveAll(ctx, strings.NewReader(input), &bytes.Buffer{}, Options{})
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected context.Canceled, but got %v", err)
+    }
+}
+
+func TestSolveAllTimesOutSlowPuzzles(t *testing.T) {
+    var out bytes.Buffer
+    summary, err := SolveAll(context.Background(), strings.NewReader(strings.Repeat(".", 81)), &out, Options{Limit: 1000000, Timeout: time.Millisecond})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if summary.Failed != 1 || !strings.Contains(out.String(), context.DeadlineExceeded.Error()) {
+        t.Errorf("Expected the puzzle to time out, but got %v", out.String())
+    }
+}
+
+func TestStepLeavesItsReceiverAlone(t *testing.T) {
+    input := unsolved.clone()
+    input.Step(ConstrainSet)
+    if same, msg := input.Equals(unsolved); !same {
+        t.Errorf("Step changed its receiver: %v", msg)
+    }
+}
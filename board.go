@@ -7,6 +7,15 @@ import (
 
 type Board []Set
 
+// A size-by-size board with every cell blank.
+func NewBoard(size int) Board {
+    board := make(Board, size)
+    for i := range board {
+        board[i] = make(Set, size)
+    }
+    return board
+}
+
 // Satisfy the Equalable interface so we can use matchers in the test.
 func (b Board) Equals(other interface{}) (bool, string) {
     switch o := other.(type) {
@@ -32,7 +41,7 @@ func (b Board) Equals(other interface{}) (bool, string) {
                     return false, fmt.Sprintf("the board (or other board) is not of equal widths!")
                 }
                 for j := range b[i] {
-                    sameLen := len(b[i][j]) == len(o[i][j])
+                    sameLen := b[i][j].Len() == o[i][j].Len()
                     if b[i][j].isEmpty() {
                         equals = equals && sameLen
                         msg += "| X "
@@ -52,3 +61,15 @@ func (b Board) Equals(other interface{}) (bool, string) {
     }
     return false, fmt.Sprintf("a Board cannot equal a %v", reflect.TypeOf(other))
 }
+
+// Deep copy of the board, so searching can't disturb the caller's cells.
+func (b Board) clone() Board {
+    out := make(Board, len(b))
+    for i := range b {
+        out[i] = make(Set, len(b[i]))
+        for j := range b[i] {
+            out[i][j] = Copy(b[i][j])
+        }
+    }
+    return out
+}
@@ -1,72 +1,96 @@
 package sudoku
 
 import (
-    "sort"
+    "fmt"
+    "math/bits"
 )
 
-type Cell []int
+// The candidate values of a cell as a bit-field: bit v-1 is set while v is
+// still possible, so a cell can hold values from 1 to 64.
+type Cell uint64
 
+// Build a cell from its candidate values. 0 is not a value, so C(0) is the
+// same empty cell as C().
 func C(vals ...int) Cell {
-    return Cell(vals)
+    c := Cell(0)
+    for _, v := range vals {
+        c |= bit(v)
+    }
+    return c
+}
+
+// The cell holding just v, or the empty cell if v is out of range.
+func bit(v int) Cell {
+    if v < 1 || v > 64 {
+        return 0
+    }
+    return 1 << uint(v - 1)
 }
 
 // Given two sets of ints, calculate the intersection
 func (prevCalcd Cell) intersection(remaining Cell) Cell {
-    if len(prevCalcd) > 0 {
-        rtn := make(Cell, 0)
-        for _, pv := range prevCalcd {
-            for _, rv := range remaining {
-                if pv == rv {
-                    rtn = append(rtn, pv)
-                }
-            }
-        }
-        return rtn
+    if prevCalcd != 0 {
+        return prevCalcd & remaining
     }
     return remaining
 }
 
 func (c Cell) union(other Cell) Cell {
-    for _, o := range other {
-        c = append(c, o)
-    }
-    sort.Ints(c)
-    return c
+    return c | other
+}
+
+func (c Cell) difference(other Cell) Cell {
+    return c &^ other
 }
 
 func (c Cell) remove(toRemove int) Cell {
-    c2 := C()
-    for _, v := range c {
-        if v != toRemove {
-            c2 = append(c2, v)
-        }
-    }
-    return c2
+    return c &^ bit(toRemove)
 }
 
 func (c Cell) isEmpty() bool {
-    return len(c) == 0
+    return c == 0
 }
 
 func (c Cell) Equals(o Cell) bool {
-    if len(c) != len(o) {
-        return false
+    return c == o
+}
+
+func (cell Cell) IsSolved() bool {
+    return cell != 0 && cell & (cell - 1) == 0
+}
+
+// Number of candidates left in the cell.
+func (c Cell) Len() int {
+    return bits.OnesCount64(uint64(c))
+}
+
+func (c Cell) Has(v int) bool {
+    return c & bit(v) != 0
+}
+
+// The value of a solved cell. For an unsolved cell this is its smallest
+// candidate, and for an empty cell 0.
+func (c Cell) Value() int {
+    if c == 0 {
+        return 0
     }
-    for _, v := range c {
-        found := false
-        for _, v2 := range o {
-            if v2 == v {
-                found = true
-                break
-            }
-        }
-        if !found {
-            return false
-        }
+    return bits.TrailingZeros64(uint64(c)) + 1
+}
+
+// The largest candidate, or 0 for an empty cell.
+func (c Cell) highest() int {
+    return 64 - bits.LeadingZeros64(uint64(c))
+}
+
+// The candidates in increasing order.
+func (c Cell) Values() []int {
+    vals := make([]int, 0, c.Len())
+    for rest := c; rest != 0; rest &= rest - 1 {
+        vals = append(vals, rest.Value())
     }
-    return true
+    return vals
 }
 
-func (cell Cell) IsSolved() bool {
-    return len(cell) == 1
+func (c Cell) String() string {
+    return fmt.Sprint(c.Values())
 }
@@ -0,0 +1,54 @@
+package sudoku
+
+import (
+    "testing"
+)
+
+func TestCellIsABitFieldOfItsValues(t *testing.T) {
+    c := C(3, 1, 2, 3)
+
+    if c.Len() != 3 || !c.Has(1) || !c.Has(2) || !c.Has(3) || c.Has(4) {
+        t.Errorf("Expected the candidates 1, 2 and 3, but got %v", c)
+    }
+    if !IsExactly(c, C(1,2,3)) {
+        t.Errorf("The order values are given in should not matter, but %v != %v", c, C(1,2,3))
+    }
+    if !C(0).isEmpty() {
+        t.Errorf("0 is not a value, so C(0) should be empty, but was %v", C(0))
+    }
+}
+
+func TestCellSetOperations(t *testing.T) {
+    a, b := C(1,2,3), C(3,4)
+
+    if !IsExactly(a.intersection(b), C(3)) {
+        t.Errorf("Intersection of %v and %v should be [3], but was %v", a, b, a.intersection(b))
+    }
+    if !IsExactly(a.union(b), C(1,2,3,4)) {
+        t.Errorf("Union of %v and %v should be [1 2 3 4], but was %v", a, b, a.union(b))
+    }
+    if !IsExactly(a.difference(b), C(1,2)) {
+        t.Errorf("Difference of %v and %v should be [1 2], but was %v", a, b, a.difference(b))
+    }
+    if !IsExactly(a.remove(2), C(1,3)) {
+        t.Errorf("Removing 2 from %v should leave [1 3], but was %v", a, a.remove(2))
+    }
+}
+
+func TestCellValues(t *testing.T) {
+    c := C(9, 4, 64)
+
+    values := c.Values()
+    if len(values) != 3 || values[0] != 4 || values[1] != 9 || values[2] != 64 {
+        t.Errorf("Expected values [4 9 64], but got %v", values)
+    }
+    if c.Value() != 4 || C(7).Value() != 7 || C().Value() != 0 {
+        t.Errorf("Value() should be the smallest candidate, or 0 for an empty cell")
+    }
+    if !C(7).IsSolved() || c.IsSolved() || C().IsSolved() {
+        t.Errorf("Only a cell with exactly one candidate is solved")
+    }
+    if Create(64).Len() != 64 {
+        t.Errorf("A 64 value cell should have 64 candidates, but had %v", Create(64).Len())
+    }
+}
@@ -0,0 +1,636 @@
+// Command sudoku solves, generates, grades and checks sudoku puzzles.
+//
+// Usage:
+//
+//     sudoku solve [variant flags] [rule flags] [-format line|grid|pretty] [file]
+//     sudoku generate [variant flags] [-evenodd] [-inequalities] [-seed n] [-count n] [-size n] [-clues n] [-symmetry s] [-minimal] [-difficulty tier] [-format f]
+//     sudoku grade [variant flags] [rule flags] [file]
+//     sudoku hint [file]
+//     sudoku validate [variant flags] [rule flags] [file]
+//     sudoku convert [variant flags] [-format line|grid|pretty] [file]
+//
+// The variant flags are -x for Sudoku-X, whose main diagonals must hold
+// every value once too, -antiknight and -antiking for puzzles where no two
+// cells a knight's or king's move apart may hold the same value, and
+// -nonconsecutive for those where no two cells next to each other may
+// hold consecutive values. The rule flags read more rules from files:
+// -cages for killer sudoku, -marks for Kropki dots, XV and other markings
+// between cells, and -drawings for thermos, arrows and sandwiches.
+//
+// A puzzle on one line may be followed by annotation layers, which shade
+// cells even or odd and put signs between them, in the format
+// sudoku.ParseAnnotated reads, on the same line or the lines after it;
+// they are written back out after boards written on one line. generate makes such puzzles with -evenodd and
+// -inequalities. hint only takes ordinary puzzles.
+//
+// Every command also takes -symbols, the alphabet boards are read and
+// written in: digits (1-9 then A-P), hex (0-9A-F), letters (A-Y), or the
+// symbols themselves in order.
+//
+// Puzzles are read from the file, or stdin if there is none, either as a
+// single board in any layout sudoku.Parse accepts or as one board per line.
+// The exit code is 2 for malformed input, rule files included, 3 for a
+// puzzle with no solution and 4 for one with more than one; when there are
+// several puzzles, the first to fail decides it.
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/tychofreeman/go-sudoku"
+)
+
+const (
+    exitOK = iota
+    exitError
+    exitMalformed
+    exitUnsolvable
+    exitMultiple
+)
+
+const usage = `usage: sudoku <command> [flags] [file]
+
+commands:
+    solve     solve puzzles
+    generate  generate puzzles with a unique solution
+    grade     rate how hard puzzles are
+    hint      show the next step for puzzles
+    validate  check puzzles are consistent and have a unique solution
+    convert   rewrite puzzles in another format
+
+Run "sudoku <command> -h" for the command's flags.
+`
+
+func main() {
+    os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Everything a command needs; out and errs stand in for stdout and stderr.
+type env struct {
+    flags *flag.FlagSet
+    in io.Reader
+    out, errs io.Writer
+    // Set by the variant and rule flags, for the commands which have them.
+    x, antiKnight, antiKing, nonConsecutive *bool
+    cages, marks, drawings *string
+    // The rules read from the files the rule flags name, and the geometry
+    // of each size of puzzle they make.
+    rules []func(*sudoku.Geometry) (*sudoku.Geometry, error)
+    geos map[int]built
+    // The annotations of the puzzle at hand, and its geometry, nil for an
+    // ordinary puzzle.
+    notes sudoku.Annotations
+    geo *sudoku.Geometry
+    // Set by -symbols once the flags are parsed.
+    symbols *string
+    alphabet sudoku.Alphabet
+}
+
+var commands = map[string]func(e *env, args []string) int{
+    "solve": solve,
+    "generate": generate,
+    "grade": grade,
+    "hint": hint,
+    "validate": validate,
+    "convert": convert,
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+    if len(args) == 0 || commands[args[0]] == nil {
+        fmt.Fprint(stderr, usage)
+        return exitError
+    }
+    flags := flag.NewFlagSet("sudoku " + args[0], flag.ContinueOnError)
+    flags.SetOutput(stderr)
+    e := &env{flags: flags, in: stdin, out: stdout, errs: stderr}
+    e.symbols = flags.String("symbols", "digits", "the `alphabet` boards are written in: digits, hex, letters, or the symbols in order")
+    return commands[args[0]](e, args[1:])
+}
+
+// The alphabets -symbols knows by name.
+var alphabets = map[string]sudoku.Alphabet{
+    "digits": sudoku.Digits,
+    "hex": sudoku.Hex,
+    "letters": sudoku.Letters,
+}
+
+// Add the flags for variants with extra rules to the command: -x for
+// Sudoku-X, -antiknight, -antiking and -nonconsecutive.
+func (e *env) variantFlags() {
+    e.x = e.flags.Bool("x", false, "Sudoku-X: the main diagonals must hold every value once too")
+    e.antiKnight = e.flags.Bool("antiknight", false, "no two cells a knight's move apart may hold the same value")
+    e.antiKing = e.flags.Bool("antiking", false, "no two cells a king's move apart may hold the same value")
+    e.nonConsecutive = e.flags.Bool("nonconsecutive", false, "no two cells next to each other in a row or column may hold consecutive values")
+}
+
+// Whether any of the variant flags are set.
+func (e *env) variant() bool {
+    return *e.x || *e.antiKnight || *e.antiKing || *e.nonConsecutive
+}
+
+// Add the flags which read a puzzle's extra rules from files to the
+// command: -cages for killer sudoku, -marks for Kropki dots, XV and the
+// like, and -drawings for thermos, arrows and sandwiches.
+func (e *env) ruleFlags() {
+    e.cages = e.flags.String("cages", "", "killer sudoku: read the cages from `file`, in the format sudoku.ParseCages reads")
+    e.marks = e.flags.String("marks", "", "read markings between cells from `file`, in the format sudoku.ParseMarkings reads")
+    e.drawings = e.flags.String("drawings", "", "read thermos, arrows and sandwiches from `file`, in the format sudoku.ParseDrawings reads")
+}
+
+// Read the files the rule flags name, once for the run.
+func (e *env) readRules() error {
+    if e.marks != nil && *e.marks != "" {
+        text, err := ioutil.ReadFile(*e.marks)
+        if err != nil {
+            return err
+        }
+        markings, err := sudoku.ParseMarkings(string(text))
+        if err != nil {
+            return err
+        }
+        e.rules = append(e.rules, func(geo *sudoku.Geometry) (*sudoku.Geometry, error) {
+            return geo.WithMarkings(markings...)
+        })
+    }
+    if e.drawings != nil && *e.drawings != "" {
+        text, err := ioutil.ReadFile(*e.drawings)
+        if err != nil {
+            return err
+        }
+        drawings, err := sudoku.ParseDrawings(string(text))
+        if err != nil {
+            return err
+        }
+        e.rules = append(e.rules, func(geo *sudoku.Geometry) (*sudoku.Geometry, error) {
+            return geo.WithDrawings(drawings)
+        })
+    }
+    if e.cages != nil && *e.cages != "" {
+        text, err := ioutil.ReadFile(*e.cages)
+        if err != nil {
+            return err
+        }
+        cages, err := sudoku.ParseCages(string(text))
+        if err != nil {
+            return err
+        }
+        e.rules = append(e.rules, func(geo *sudoku.Geometry) (*sudoku.Geometry, error) {
+            return geo.WithCages(cages...)
+        })
+    }
+    return nil
+}
+
+// Rules which don't fit the puzzle, like a cage off the board, make the
+// input as malformed as a bad board does.
+type malformedError struct {
+    error
+}
+
+func (e malformedError) Unwrap() error {
+    return e.error
+}
+
+// A geometry built for a size of puzzle, or why it couldn't be.
+type built struct {
+    geo *sudoku.Geometry
+    err error
+}
+
+// The geometry of puzzles of the size, with the variants and the rules
+// read from files, or nil for ordinary ones. It is built once for each
+// size.
+func (e *env) geometry(size int) (*sudoku.Geometry, error) {
+    if built, ok := e.geos[size]; ok {
+        return built.geo, built.err
+    }
+    var geo *sudoku.Geometry
+    standard := func() *sudoku.Geometry {
+        if geo == nil {
+            geo = sudoku.StandardGeometry(size)
+        }
+        return geo
+    }
+    if e.x != nil && *e.x {
+        geo = standard().WithDiagonals()
+    }
+    if e.antiKnight != nil && *e.antiKnight {
+        geo = standard().WithAntiKnight()
+    }
+    if e.antiKing != nil && *e.antiKing {
+        geo = standard().WithAntiKing()
+    }
+    if e.nonConsecutive != nil && *e.nonConsecutive {
+        geo = standard().WithNonConsecutive()
+    }
+    var err error
+    for _, rule := range e.rules {
+        if geo, err = rule(standard()); err != nil {
+            geo, err = nil, malformedError{err}
+            break
+        }
+    }
+    if e.geos == nil {
+        e.geos = map[int]built{}
+    }
+    e.geos[size] = built{geo, err}
+    return geo, err
+}
+
+// The geometry of the puzzle at hand: that of its size, with its
+// annotations.
+func (e *env) puzzleGeometry(size int) (*sudoku.Geometry, error) {
+    geo, err := e.geometry(size)
+    if err != nil || e.notes.IsEmpty() {
+        return geo, err
+    }
+    if geo == nil {
+        geo = sudoku.StandardGeometry(size)
+    }
+    if geo, err = geo.WithAnnotations(e.notes); err != nil {
+        return nil, malformedError{err}
+    }
+    return geo, nil
+}
+
+// Solve the board, as Board.SolveE does, in the puzzle's geometry.
+func (e *env) solve(board sudoku.Board) (sudoku.Board, error) {
+    if e.geo == nil {
+        return board.SolveE(context.Background())
+    }
+    return e.geo.Solve(context.Background(), board)
+}
+
+// Grade the board, as Board.Grade does, in the puzzle's geometry.
+func (e *env) grade(board sudoku.Board) (sudoku.Grade, error) {
+    if e.geo == nil {
+        return board.Grade()
+    }
+    return e.geo.Grade(board)
+}
+
+// Parse the command's flags, returning false if they are wrong.
+func (e *env) parse(args []string) bool {
+    if e.flags.Parse(args) != nil || e.flags.NArg() > 1 {
+        return false
+    }
+    alphabet, ok := alphabets[*e.symbols]
+    if !ok {
+        var err error
+        if alphabet, err = sudoku.NewAlphabet(*e.symbols); err != nil {
+            fmt.Fprintln(e.errs, err)
+            return false
+        }
+    }
+    e.alphabet = alphabet
+    return true
+}
+
+// The board as one line, in the alphabet, followed by the layers of the
+// puzzle's annotations.
+func (e *env) line(board sudoku.Board) string {
+    return e.alphabet.FormatAnnotated(board, e.notes)
+}
+
+// A puzzle's text, and the line of the input it starts on.
+type source struct {
+    text string
+    line int
+}
+
+// Each puzzle in the file named by the arguments, or stdin.
+func (e *env) puzzles() ([]source, error) {
+    in := e.in
+    if name := e.flags.Arg(0); name != "" && name != "-" {
+        f, err := os.Open(name)
+        if err != nil {
+            return nil, err
+        }
+        defer f.Close()
+        in = f
+    }
+    text, err := ioutil.ReadAll(in)
+    if err != nil {
+        return nil, err
+    }
+
+    if _, err := e.alphabet.Parse(string(text)); err == nil {
+        return []source{{string(text), 1}}, nil
+    }
+    puzzles := []source{}
+    for i, line := range strings.Split(string(text), "\n") {
+        line = strings.TrimSpace(line)
+        switch {
+            case line == "" || line[0] == '#':
+            case isLayers(line) && len(puzzles) > 0:
+                // Annotation layers on lines of their own go with the
+                // board before them.
+                puzzles[len(puzzles) - 1].text += "\n" + line
+            default:
+                puzzles = append(puzzles, source{line, i + 1})
+        }
+    }
+    if len(puzzles) == 0 {
+        puzzles = append(puzzles, source{"", 1})
+    }
+    return puzzles, nil
+}
+
+// Whether the line is only annotation layers, as sudoku.ParseAnnotated
+// reads them, with a marker on at least one.
+func isLayers(line string) bool {
+    for _, word := range strings.Fields(line) {
+        if strings.Trim(word, ".eo<>^v") != "" {
+            return false
+        }
+    }
+    return strings.ContainsAny(line, "eo<>^v")
+}
+
+// Run do on every puzzle, reporting failures on stderr. Returns the exit
+// code for the first failure.
+func (e *env) each(do func(board sudoku.Board) error) int {
+    if err := e.readRules(); err != nil {
+        fmt.Fprintln(e.errs, err)
+        return exitCode(err)
+    }
+    puzzles, err := e.puzzles()
+    if err != nil {
+        fmt.Fprintln(e.errs, err)
+        return exitError
+    }
+    code := exitOK
+    for _, p := range puzzles {
+        board, notes, err := e.alphabet.ParseAnnotated(p.text)
+        e.notes = notes
+        if parseErr, ok := err.(*sudoku.ParseError); ok {
+            // Counted from the puzzle's own first line.
+            parseErr.Line += p.line - 1
+        }
+        if err == nil {
+            e.geo, err = e.puzzleGeometry(len(board))
+        }
+        if err == nil {
+            err = do(board)
+        }
+        if err != nil {
+            fmt.Fprintln(e.errs, err)
+            if code == exitOK {
+                code = exitCode(err)
+            }
+        }
+    }
+    return code
+}
+
+func exitCode(err error) int {
+    var parseErr *sudoku.ParseError
+    var malformed malformedError
+    switch {
+        case errors.As(err, &parseErr), errors.As(err, &malformed):
+            return exitMalformed
+        case errors.Is(err, sudoku.ErrMultipleSolutions):
+            return exitMultiple
+        case errors.Is(err, sudoku.ErrContradiction), errors.Is(err, sudoku.ErrNoProgress):
+            return exitUnsolvable
+    }
+    return exitError
+}
+
+// Write a board as one line, as a grid with a line per row, or as the grid
+// GoString draws.
+func (e *env) format(name string) (func(sudoku.Board) string, error) {
+    switch name {
+        case "line":
+            return e.line, nil
+        case "grid":
+            return func(b sudoku.Board) string {
+                line := []rune(e.alphabet.Format(b))
+                rows := []string{}
+                for i := 0; i < len(line); i += len(b) {
+                    rows = append(rows, string(line[i:i + len(b)]))
+                }
+                return strings.Join(rows, "\n")
+            }, nil
+        case "pretty":
+            return func(b sudoku.Board) string {
+                return e.alphabet.Draw(b, e.geo)
+            }, nil
+    }
+    return nil, fmt.Errorf("unknown format %q; use line, grid or pretty", name)
+}
+
+// Write the text to stdout, ending it in a new line unless it already
+// ends in one, as drawn grids do.
+func (e *env) writeLine(text string) {
+    if !strings.HasSuffix(text, "\n") {
+        text += "\n"
+    }
+    fmt.Fprint(e.out, text)
+}
+
+// Add a -format flag to the command, returning a function for the format
+// it names once the flags are parsed.
+func (e *env) formatFlag() func() (func(sudoku.Board) string, error) {
+    name := e.flags.String("format", "line", "output `format`: line, grid or pretty")
+    return func() (func(sudoku.Board) string, error) {
+        return e.format(*name)
+    }
+}
+
+func solve(e *env, args []string) int {
+    e.variantFlags()
+    e.ruleFlags()
+    formatter := e.formatFlag()
+    if !e.parse(args) {
+        return exitError
+    }
+    write, err := formatter()
+    if err != nil {
+        fmt.Fprintln(e.errs, err)
+        return exitError
+    }
+    return e.each(func(board sudoku.Board) error {
+        solution, err := e.solve(board)
+        if solution != nil {
+            e.writeLine(write(solution))
+        }
+        return err
+    })
+}
+
+func generate(e *env, args []string) int {
+    seed := e.flags.Int64("seed", time.Now().UnixNano(), "seed for the first puzzle; the same seed gives the same puzzle")
+    count := e.flags.Int("count", 1, "how many puzzles to generate")
+    size := e.flags.Int("size", 9, "length of the board's sides")
+    clues := e.flags.Int("clues", 0, "stop removing clues at this many; 0 removes all it can")
+    symmetry := e.flags.String("symmetry", "none", "symmetry of the clues: none, rotational, diagonal or mirror")
+    minimal := e.flags.Bool("minimal", false, "make every clue necessary")
+    difficulty := e.flags.String("difficulty", "", "only keep puzzles of this tier: easy, medium, hard, fiendish or diabolical")
+    evenOdd := e.flags.Bool("evenodd", false, "shade every cell of the puzzle even or odd")
+    inequalities := e.flags.Bool("inequalities", false, "put a sign between every two cells next to each other in a box")
+    e.variantFlags()
+    formatter := e.formatFlag()
+    if !e.parse(args) || e.flags.NArg() > 0 {
+        return exitError
+    }
+    var markers sudoku.Markers
+    if *evenOdd {
+        markers |= sudoku.ParityMarkers
+    }
+    if *inequalities {
+        markers |= sudoku.InequalityMarkers
+    }
+    if (e.variant() || markers != 0) && *difficulty != "" {
+        fmt.Fprintln(e.errs, "only ordinary puzzles can be generated by difficulty")
+        return exitError
+    }
+    write, err := formatter()
+    if err != nil {
+        fmt.Fprintln(e.errs, err)
+        return exitError
+    }
+
+    geo, err := e.geometry(*size)
+    if err != nil {
+        fmt.Fprintln(e.errs, err)
+        return exitCode(err)
+    }
+    e.geo = geo
+    opts := sudoku.GenerateOptions{Size: *size, Seed: *seed, Clues: *clues, Minimal: *minimal, Geometry: geo}
+    found := false
+    for s := sudoku.NoSymmetry; s <= sudoku.Mirror; s++ {
+        if s.String() == *symmetry {
+            opts.Symmetry, found = s, true
+        }
+    }
+    if !found {
+        fmt.Fprintf(e.errs, "unknown symmetry %q\n", *symmetry)
+        return exitError
+    }
+    tier, found := sudoku.Easy, *difficulty == ""
+    for t := sudoku.Easy; t <= sudoku.Diabolical; t++ {
+        if strings.EqualFold(t.String(), *difficulty) {
+            tier, found = t, true
+        }
+    }
+    if !found {
+        fmt.Fprintf(e.errs, "unknown difficulty %q\n", *difficulty)
+        return exitError
+    }
+
+    // Puzzles of the wanted tier are found by trying seeds in turn.
+    const attempts = 1000
+    for made, tries := 0, 0; made < *count; opts.Seed++ {
+        var puzzle sudoku.Board
+        if markers != 0 {
+            if puzzle, e.notes, err = sudoku.GenerateAnnotated(opts, markers); err == nil {
+                e.geo, err = e.puzzleGeometry(*size)
+            }
+        } else {
+            puzzle, err = sudoku.Generate(opts)
+        }
+        if err != nil {
+            fmt.Fprintln(e.errs, err)
+            return exitError
+        }
+        if *difficulty != "" {
+            if grade, err := puzzle.Grade(); err != nil || grade.Tier != tier {
+                if tries++; tries == attempts {
+                    fmt.Fprintf(e.errs, "no %v puzzle in %d attempts\n", tier, attempts)
+                    return exitError
+                }
+                continue
+            }
+        }
+        e.writeLine(write(puzzle))
+        made, tries = made + 1, 0
+    }
+    return exitOK
+}
+
+func grade(e *env, args []string) int {
+    e.variantFlags()
+    e.ruleFlags()
+    if !e.parse(args) {
+        return exitError
+    }
+    return e.each(func(board sudoku.Board) error {
+        grade, err := e.grade(board)
+        if err != nil {
+            return err
+        }
+        fmt.Fprintf(e.out, "%v\t%.1f\t%v\n", e.line(board), grade.Rating, grade.Tier)
+        for _, use := range grade.Used {
+            fmt.Fprintf(e.out, "\t%.1f\t%s x %d\n", use.Difficulty, use.Technique, use.Count)
+        }
+        return nil
+    })
+}
+
+func hint(e *env, args []string) int {
+    if !e.parse(args) {
+        return exitError
+    }
+    return e.each(func(board sudoku.Board) error {
+        if !e.notes.IsEmpty() {
+            return fmt.Errorf("%v: hints are only given for ordinary puzzles", e.line(board))
+        }
+        if _, err := board.SolveE(context.Background()); err != nil {
+            return err
+        }
+        h, err := board.Hint()
+        if errors.Is(err, sudoku.ErrNoProgress) {
+            if board.IsSolved() {
+                fmt.Fprintf(e.out, "%v\tsolved\n", e.line(board))
+                return nil
+            }
+            return fmt.Errorf("%v: no technique makes progress; the next step is a guess", e.line(board))
+        }
+        if err != nil {
+            return err
+        }
+        fmt.Fprintf(e.out, "%v\t%v\t%v\n", e.line(board), h.Target, h.Deduction)
+        return nil
+    })
+}
+
+func validate(e *env, args []string) int {
+    e.variantFlags()
+    e.ruleFlags()
+    if !e.parse(args) {
+        return exitError
+    }
+    return e.each(func(board sudoku.Board) error {
+        if _, err := e.solve(board); err != nil {
+            return err
+        }
+        fmt.Fprintf(e.out, "%v\tok\n", e.line(board))
+        return nil
+    })
+}
+
+func convert(e *env, args []string) int {
+    e.variantFlags()
+    formatter := e.formatFlag()
+    if !e.parse(args) {
+        return exitError
+    }
+    write, err := formatter()
+    if err != nil {
+        fmt.Fprintln(e.errs, err)
+        return exitError
+    }
+    return e.each(func(board sudoku.Board) error {
+        e.writeLine(write(board))
+        return nil
+    })
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+    "bytes"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/tychofreeman/go-sudoku"
+)
+
+const puzzle = ".1.6.7..4.42......87.3..6...8..7..2....893....3..6..1...8..6.45......17.4..9.8.6."
+const solution = "913627584642589731875341692589174326261893457734265819128736945396452178457918263"
+
+func runWith(args []string, input string) (int, string, string) {
+    var out, errs bytes.Buffer
+    code := run(args, strings.NewReader(input), &out, &errs)
+    return code, out.String(), errs.String()
+}
+
+func mustParse(t *testing.T, text string) sudoku.Board {
+    board, err := sudoku.Parse(text)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    return board
+}
+
+func TestSolvesFromStdin(t *testing.T) {
+    code, out, _ := runWith([]string{"solve"}, puzzle)
+    if code != exitOK || out != solution + "\n" {
+        t.Errorf("Expected the solution and %d, but got %q and %d", exitOK, out, code)
+    }
+}
+
+func TestSolvesAPuzzlePerLine(t *testing.T) {
+    code, out, _ := runWith([]string{"solve", "-format", "grid"}, puzzle + "\n\n" + puzzle + "\n")
+    if code != exitOK || strings.Count(out, "\n") != 18 || !strings.HasPrefix(out, "913627584\n642589731\n") {
+        t.Errorf("Expected two solved grids, but got %q and %d", out, code)
+    }
+}
+
+func TestSolvesTheFileNamed(t *testing.T) {
+    code, out, _ := runWith([]string{"solve", "../../solved.txt"}, "")
+    if code != exitOK || out != solution + "\n" {
+        t.Errorf("Expected the solution and %d, but got %q and %d", exitOK, out, code)
+    }
+}
+
+func TestExitCodesSayWhatWentWrong(t *testing.T) {
+    for input, expected := range map[string]int{
+        "12x4": exitMalformed,
+        "11..............": exitUnsolvable,
+        "1...............": exitMultiple,
+    } {
+        for _, command := range []string{"solve", "validate"} {
+            if code, _, errs := runWith([]string{command}, input); code != expected || errs == "" {
+                t.Errorf("%s %q: expected exit code %d and an error, but got %d and %q", command, input, expected, code, errs)
+            }
+        }
+    }
+    if code, _, _ := runWith([]string{"unknown"}, ""); code != exitError {
+        t.Errorf("Expected exit code %d for an unknown command, but got %d", exitError, code)
+    }
+}
+
+func TestParseErrorsGiveTheLineOfThePuzzle(t *testing.T) {
+    bad := puzzle[:79] + "x" + puzzle[80:]
+    code, _, errs := runWith([]string{"solve"}, "# two puzzles\n" + puzzle + "\n\n" + bad + "\n")
+    expected := "sudoku: line 4, column 80: unexpected 'x'\n"
+    if code != exitMalformed || errs != expected {
+        t.Errorf("Expected %q and %d, but got %q and %d", expected, exitMalformed, errs, code)
+    }
+}
+
+func TestGeneratesTheSamePuzzleForASeed(t *testing.T) {
+    _, first, _ := runWith([]string{"generate", "-seed", "7", "-symmetry", "rotational"}, "")
+    code, second, _ := runWith([]string{"generate", "-seed", "7", "-symmetry", "rotational"}, "")
+    if code != exitOK || first != second || len(first) != 82 {
+        t.Errorf("Expected the same puzzle twice, but got %q and %q", first, second)
+    }
+    if code, out, _ := runWith([]string{"validate"}, first); code != exitOK {
+        t.Errorf("Expected a valid puzzle, but got %q", out)
+    }
+}
+
+func TestGeneratesPuzzlesOfADifficulty(t *testing.T) {
+    code, out, errs := runWith([]string{"generate", "-seed", "1", "-count", "2", "-difficulty", "easy"}, "")
+    if code != exitOK || strings.Count(out, "\n") != 2 {
+        t.Fatalf("Expected two puzzles, but got %q and %q", out, errs)
+    }
+    _, grades, _ := runWith([]string{"grade"}, out)
+    if strings.Count(grades, "\teasy\n") != 2 {
+        t.Errorf("Expected two easy puzzles, but got %q", grades)
+    }
+}
+
+func TestGivesAHint(t *testing.T) {
+    code, out, _ := runWith([]string{"hint"}, puzzle)
+    if code != exitOK || !strings.HasPrefix(out, puzzle + "\tr") {
+        t.Errorf("Expected a hint, but got %q", out)
+    }
+}
+
+func TestConvertsBetweenFormats(t *testing.T) {
+    _, grid, _ := runWith([]string{"convert", "-format", "grid"}, puzzle)
+    code, line, _ := runWith([]string{"convert"}, grid)
+    if code != exitOK || line != puzzle + "\n" {
+        t.Errorf("Expected %q back, but got %q", puzzle, line)
+    }
+    code, pretty, _ := runWith([]string{"convert", "-format", "pretty"}, puzzle + "\n" + puzzle)
+    if drawn := sudoku.Digits.Draw(mustParse(t, puzzle), nil); code != exitOK || pretty != drawn + drawn {
+        t.Errorf("Expected two drawn grids with nothing between them, but got\n%s", pretty)
+    }
+}
+
+func TestGeneratesAndValidatesSudokuX(t *testing.T) {
+    code, puzzle, errs := runWith([]string{"generate", "-x", "-seed", "2"}, "")
+    if code != exitOK {
+        t.Fatalf("Unexpected failure %q", errs)
+    }
+    if code, _, errs := runWith([]string{"validate", "-x"}, puzzle); code != exitOK {
+        t.Errorf("Expected the puzzle to be valid, but got %q", errs)
+    }
+    if code, out, _ := runWith([]string{"solve", "-x", "-format", "pretty"}, puzzle); code != exitOK || !strings.HasPrefix(out, "*") {
+        t.Errorf("Expected a solution with the diagonals marked, but got %q", out)
+    }
+}
+
+func TestSolvesAndGradesKillerSudoku(t *testing.T) {
+    cages := "../../testdata/killer.txt"
+    blank := strings.Repeat(".", 81)
+    if code, out, errs := runWith([]string{"solve", "-cages", cages}, blank); code != exitOK || out != solution + "\n" {
+        t.Errorf("Expected the solution, but got %q and %q", out, errs)
+    }
+    if code, out, errs := runWith([]string{"grade", "-cages", cages}, blank); code != exitOK || !strings.Contains(out, "Cage sum") {
+        t.Errorf("Expected a grade using the cages, but got %q and %q", out, errs)
+    }
+    if code, _, _ := runWith([]string{"solve", "-cages", "main.go"}, blank); code != exitMalformed {
+        t.Errorf("Expected exit code %d for bad cages, but got %d", exitMalformed, code)
+    }
+}
+
+func TestReadsAndWritesOtherAlphabets(t *testing.T) {
+    letters := strings.NewReplacer("1", "A", "2", "B", "3", "C", "4", "D", "5", "E", "6", "F", "7", "G", "8", "H", "9", "I")
+    code, out, errs := runWith([]string{"solve", "-symbols", "letters"}, letters.Replace(puzzle))
+    if code != exitOK || out != letters.Replace(solution) + "\n" {
+        t.Errorf("Expected the solution in letters, but got %q and %q", out, errs)
+    }
+    symbols := strings.NewReplacer("1", "!", "2", "@", "3", "#", "4", "$", "5", "%", "6", "^", "7", "&", "8", "*", "9", "(")
+    code, out, errs = runWith([]string{"convert", "-symbols", "!@#$%^&*(", "-format", "grid"}, symbols.Replace(puzzle))
+    if code != exitOK || strings.Replace(out, "\n", "", -1) != symbols.Replace(puzzle) {
+        t.Errorf("Expected the puzzle in symbols, but got %q and %q", out, errs)
+    }
+    if code, _, _ := runWith([]string{"solve", "-symbols", "1123"}, puzzle); code != exitError {
+        t.Errorf("Expected %d for a bad alphabet, but got %d", exitError, code)
+    }
+}
+
+func TestGeneratesAndValidatesAntiKnightAndAntiKing(t *testing.T) {
+    code, puzzle, errs := runWith([]string{"generate", "-antiknight", "-antiking", "-seed", "3"}, "")
+    if code != exitOK {
+        t.Fatalf("Unexpected failure %q", errs)
+    }
+    if code, _, errs := runWith([]string{"validate", "-antiknight", "-antiking"}, puzzle); code != exitOK {
+        t.Errorf("Expected the puzzle to be valid, but got %q", errs)
+    }
+    if code, _, _ := runWith([]string{"validate"}, puzzle); code != exitMultiple {
+        t.Errorf("Expected %d without the rules, but got %d", exitMultiple, code)
+    }
+    if code, _, _ := runWith([]string{"generate", "-antiking", "-difficulty", "easy"}, ""); code != exitError {
+        t.Errorf("Expected %d for a variant by difficulty, but got %d", exitError, code)
+    }
+}
+
+func TestSolvesKropkiFromMarkings(t *testing.T) {
+    code, out, errs := runWith([]string{"solve", "-marks", "../../testdata/kropki.txt"}, strings.Repeat(".", 81))
+    if code != exitOK || out != solution + "\n" {
+        t.Errorf("Expected the solution, but got %q and %q", out, errs)
+    }
+}
+
+func TestMalformedRuleFilesAreMalformedInput(t *testing.T) {
+    dir, err := ioutil.TempDir("", "sudoku")
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    defer os.RemoveAll(dir)
+    // A file that can't be parsed stops the run; rules which don't fit are
+    // reported for each puzzle. The last is only off the 4x4 boards.
+    for marks, errors := range map[string]int{"white r1c1": 1, "white r1c1 r1c1": 2, "white r1c1 r9c9": 2} {
+        name := filepath.Join(dir, "marks.txt")
+        if err := ioutil.WriteFile(name, []byte(marks), 0644); err != nil {
+            t.Fatalf("Unexpected error %v", err)
+        }
+        code, out, errs := runWith([]string{"solve", "-marks", name, "-format", "pretty"}, "1...............\n1...............\n")
+        if code != exitMalformed || out != "" || strings.Count(errs, "\n") != errors {
+            t.Errorf("%q: expected %d and %d errors, but got %d, %q and %q", marks, exitMalformed, errors, code, out, errs)
+        }
+    }
+}
+
+func TestSolvesDrawingsFromFile(t *testing.T) {
+    puzzle := "...........2.....................3...........7............3......................"
+    code, out, errs := runWith([]string{"solve", "-drawings", "../../testdata/drawings.txt"}, puzzle)
+    if code != exitOK || out != solution + "\n" {
+        t.Errorf("Expected the solution, but got %q and %q", out, errs)
+    }
+    if code, _, _ := runWith([]string{"validate"}, puzzle); code != exitMultiple {
+        t.Errorf("Expected %d without the drawings, but got %d", exitMultiple, code)
+    }
+}
+
+func TestAnnotatedPuzzles(t *testing.T) {
+    code, out, errs := runWith([]string{"generate", "-evenodd", "-inequalities", "-seed", "1"}, "")
+    if code != exitOK || len(strings.Fields(out)) != 4 {
+        t.Fatalf("Expected a board and three layers, but got %q and %q", out, errs)
+    }
+    if code, checked, errs := runWith([]string{"validate"}, out); code != exitOK || !strings.HasPrefix(checked, strings.TrimSpace(out) + "\tok") {
+        t.Errorf("Expected the puzzle to be valid, but got %q and %q", checked, errs)
+    }
+    if code, converted, _ := runWith([]string{"convert"}, out); code != exitOK || converted != out {
+        t.Errorf("Expected the annotations to be kept, but got %q", converted)
+    }
+    // Layers may go on lines of their own, after their board.
+    apart := strings.Replace(out, " ", "\n", -1)
+    if code, converted, errs := runWith([]string{"convert"}, apart + apart); code != exitOK || converted != out + out {
+        t.Errorf("Expected the layers to be read with their boards, but got %q and %q", converted, errs)
+    }
+    if code, _, _ := runWith([]string{"hint"}, out); code != exitError {
+        t.Errorf("Expected %d for a hint, but got %d", exitError, code)
+    }
+    if code, _, _ := runWith([]string{"generate", "-evenodd", "-difficulty", "easy"}, ""); code != exitError {
+        t.Errorf("Expected %d for markers by difficulty, but got %d", exitError, code)
+    }
+}
@@ -0,0 +1,427 @@
+package sudoku
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// A thermometer: the values increase along its cells, from the bulb.
+type Thermo []Pos
+
+// Written as "thermo r1c1 r1c2 r1c3", bulb first, as ParseDrawings reads it.
+func (t Thermo) String() string {
+    return "thermo " + strings.Join(posStrings(t), " ")
+}
+
+// An arrow: the value in its circle is the sum of those along its path,
+// which may repeat unless they share a unit.
+type Arrow struct {
+    Circle Pos
+    Path   []Pos
+}
+
+// Written as "arrow r5c5 r5c6 r5c7", circle first, as ParseDrawings reads it.
+func (a Arrow) String() string {
+    return fmt.Sprintf("arrow %v %s", a.Circle, strings.Join(posStrings(a.Path), " "))
+}
+
+// A sandwich clue: the values between the 1 and the largest value in a
+// row or column add up to Sum. Kind is Row or Column.
+type Sandwich struct {
+    Kind  UnitKind
+    Index int
+    Sum   int
+}
+
+// Written as "sandwich r3 15" or "sandwich c2 0", as ParseDrawings reads it.
+func (s Sandwich) String() string {
+    return fmt.Sprintf("sandwich %c%d %d", s.Kind.String()[0], s.Index + 1, s.Sum)
+}
+
+// Everything drawn on a thermo, arrow or sandwich puzzle besides its
+// givens.
+type Drawings struct {
+    Thermos    []Thermo
+    Arrows     []Arrow
+    Sandwiches []Sandwich
+}
+
+// One drawing to a line, as ParseDrawings reads them.
+func (d Drawings) String() string {
+    lines := []string{}
+    for _, t := range d.Thermos {
+        lines = append(lines, t.String())
+    }
+    for _, a := range d.Arrows {
+        lines = append(lines, a.String())
+    }
+    for _, s := range d.Sandwiches {
+        lines = append(lines, s.String())
+    }
+    return strings.Join(lines, "\n") + "\n"
+}
+
+func posStrings(cells []Pos) []string {
+    out := make([]string, len(cells))
+    for i, p := range cells {
+        out[i] = p.String()
+    }
+    return out
+}
+
+// A copy of the geometry with the drawings' rules. Returns an error if a
+// drawing leaves the board, crosses itself, or can't be kept to whatever
+// the values.
+func (geo *Geometry) WithDrawings(d Drawings) (*Geometry, error) {
+    t := &thermos{}
+    for i, thermo := range d.Thermos {
+        if err := geo.checkLine("thermo", i, thermo); err != nil {
+            return nil, err
+        }
+        if len(thermo) > geo.values() {
+            return nil, fmt.Errorf("sudoku: thermo %d is longer than the %d values", i + 1, geo.values())
+        }
+        t.units = append(t.units, Unit{ThermoUnit, i, thermo})
+    }
+    a := &arrows{}
+    for i, arrow := range d.Arrows {
+        if len(arrow.Path) == 0 {
+            return nil, fmt.Errorf("sudoku: arrow %d has no path", i + 1)
+        }
+        cells := append([]Pos{arrow.Circle}, arrow.Path...)
+        if err := geo.checkLine("arrow", i, cells); err != nil {
+            return nil, err
+        }
+        a.units = append(a.units, Unit{ArrowUnit, i, cells})
+    }
+    s := &sandwiches{}
+    for i, sandwich := range d.Sandwiches {
+        if sandwich.Kind != Row && sandwich.Kind != Column || sandwich.Index < 0 || sandwich.Index >= geo.Size {
+            return nil, fmt.Errorf("sudoku: sandwich %d isn't in a row or column of the board", i + 1)
+        }
+        if most := geo.values() * (geo.values() + 1) / 2 - 1 - geo.values(); sandwich.Sum < 0 || sandwich.Sum > most {
+            return nil, fmt.Errorf("sudoku: sandwich %d can't add up to %d", i + 1, sandwich.Sum)
+        }
+        u := Unit{sandwich.Kind, sandwich.Index, make([]Pos, geo.Size)}
+        for j := range u.Cells {
+            if u.Kind == Row {
+                u.Cells[j] = Pos{u.Index, j}
+            } else {
+                u.Cells[j] = Pos{j, u.Index}
+            }
+        }
+        s.units = append(s.units, u)
+        s.sums = append(s.sums, sandwich.Sum)
+    }
+
+    constraints := []Constraint{}
+    if len(t.units) > 0 {
+        constraints = append(constraints, t)
+    }
+    if len(a.units) > 0 {
+        constraints = append(constraints, a)
+    }
+    if len(s.units) > 0 {
+        constraints = append(constraints, s)
+    }
+    return geo.with(constraints...), nil
+}
+
+// Check the cells of the i'th drawing of a kind are on the board, and
+// don't repeat.
+func (geo *Geometry) checkLine(kind string, i int, cells []Pos) error {
+    seen := map[Pos]bool{}
+    for _, p := range cells {
+        if p.Row < 0 || p.Row >= geo.Size || p.Col < 0 || p.Col >= geo.Size {
+            return fmt.Errorf("sudoku: %s %d leaves the board at %v", kind, i + 1, p)
+        }
+        if seen[p] {
+            return fmt.Errorf("sudoku: %s %d crosses itself at %v", kind, i + 1, p)
+        }
+        seen[p] = true
+    }
+    return nil
+}
+
+// Each cell of a thermo must be more than the smallest value the cell
+// before it can hold, and less than the largest the cell after it can.
+type thermos struct {
+    units []Unit
+}
+
+func (*thermos) Name() string {
+    return "Thermo"
+}
+
+func (*thermos) Difficulty() float64 {
+    return 1.9
+}
+
+func (t *thermos) Apply(g *Grid) Result {
+    r := Result{}
+    for i := range t.units {
+        u := &t.units[i]
+        left := make([]Cell, len(u.Cells))
+        low := 0
+        for j, p := range u.Cells {
+            left[j] = g.At(p).difference(Create(low))
+            low = left[j].Value()
+        }
+        high := g.values + 1
+        for j := len(u.Cells) - 1; j >= 0; j-- {
+            left[j] &= Create(high - 1)
+            high = left[j].highest()
+        }
+        d := Deduction{Technique: t.Name(), Unit: u, Cells: u.Cells}
+        for j, p := range u.Cells {
+            d.Values |= left[j]
+            d.Eliminations = append(d.Eliminations, Elimination{p, g.At(p).difference(left[j])})
+        }
+        r.add(g, d)
+    }
+    return r
+}
+
+// A thermo is broken once two of its solved cells are too close in value
+// for the cells between them.
+func (t *thermos) Check(g *Grid) error {
+    for i, u := range t.units {
+        last, at := 0, -1
+        for j, p := range u.Cells {
+            if cell := g.At(p); cell.IsSolved() {
+                if at >= 0 && cell.Value() - last < j - at {
+                    return &ContradictionError{ThermoUnit, i, cell.Value(), fmt.Sprintf("%v can't be %d, %d cells after a %d", p, cell.Value(), j - at, last)}
+                }
+                last, at = cell.Value(), j
+            }
+        }
+    }
+    return nil
+}
+
+// The circle of an arrow can only hold sums its path can make, and the
+// path only values which make one of them.
+type arrows struct {
+    units []Unit
+}
+
+func (*arrows) Name() string {
+    return "Arrow"
+}
+
+func (*arrows) Difficulty() float64 {
+    return 2.0
+}
+
+func (a *arrows) Apply(g *Grid) Result {
+    r := Result{}
+    for i := range a.units {
+        u := &a.units[i]
+        circle, path := u.Cells[0], u.Cells[1:]
+        sums := C()
+        support := make([]Cell, len(path))
+        candidates := make([]Cell, len(path))
+        for _, sum := range g.At(circle).Values() {
+            for j, p := range path {
+                candidates[j] = g.At(p)
+                if g.Sees(circle, p) {
+                    candidates[j] = candidates[j].remove(sum)
+                }
+            }
+            found := sumSupportOf(g, path, candidates, sum, false)
+            if found[0] == 0 {
+                continue
+            }
+            sums |= C(sum)
+            for j := range support {
+                support[j] |= found[j]
+            }
+        }
+        d := Deduction{Technique: a.Name(), Unit: u, Cells: u.Cells, Values: sums}
+        d.Eliminations = append(d.Eliminations, Elimination{circle, g.At(circle).difference(sums)})
+        for j, p := range path {
+            d.Eliminations = append(d.Eliminations, Elimination{p, g.At(p).difference(support[j])})
+        }
+        r.add(g, d)
+    }
+    return r
+}
+
+// An arrow is broken once its solved path adds up to more than its
+// circle, or all of it to anything else.
+func (a *arrows) Check(g *Grid) error {
+    for i, u := range a.units {
+        circle := g.At(u.Cells[0])
+        total, solved := 0, 0
+        for _, p := range u.Cells[1:] {
+            if cell := g.At(p); cell.IsSolved() {
+                total += cell.Value()
+                solved++
+            }
+        }
+        if circle.IsSolved() && (total > circle.Value() || solved == len(u.Cells) - 1 && total != circle.Value()) {
+            return &ContradictionError{ArrowUnit, i, circle.Value(), fmt.Sprintf("the path adds up to %d, not %d", total, circle.Value())}
+        }
+    }
+    return nil
+}
+
+// The 1 and the largest value of a sandwich's line can only go where the
+// cells between them can make its sum.
+type sandwiches struct {
+    units []Unit
+    sums  []int
+}
+
+func (*sandwiches) Name() string {
+    return "Sandwich"
+}
+
+func (*sandwiches) Difficulty() float64 {
+    return 2.6
+}
+
+func (s *sandwiches) Apply(g *Grid) Result {
+    r := Result{}
+    crusts := C(1, g.values)
+    for i := range s.units {
+        u := &s.units[i]
+        n := len(u.Cells)
+        support := make([]Cell, n)
+        candidates := make([]Cell, n)
+        for j, p := range u.Cells {
+            candidates[j] = g.At(p).difference(crusts)
+        }
+        // Try the 1 and the largest value in each pair of cells in turn.
+        for a := 0; a < n; a++ {
+            for b := a + 1; b < n; b++ {
+                for _, ends := range [][2]int{{1, g.values}, {g.values, 1}} {
+                    if !g.At(u.Cells[a]).Has(ends[0]) || !g.At(u.Cells[b]).Has(ends[1]) {
+                        continue
+                    }
+                    inside := sumSupportOf(g, u.Cells[a + 1:b], candidates[a + 1:b], s.sums[i], true)
+                    if b == a + 1 && s.sums[i] != 0 || b > a + 1 && inside[0] == 0 {
+                        continue
+                    }
+                    support[a] |= C(ends[0])
+                    support[b] |= C(ends[1])
+                    for j := range inside {
+                        support[a + 1 + j] |= inside[j]
+                    }
+                    for j := 0; j < n; j++ {
+                        if j < a || j > b {
+                            support[j] |= candidates[j]
+                        }
+                    }
+                }
+            }
+        }
+        d := Deduction{Technique: s.Name(), Unit: u, Cells: u.Cells}
+        for j, p := range u.Cells {
+            d.Eliminations = append(d.Eliminations, Elimination{p, g.At(p).difference(support[j])})
+        }
+        r.add(g, d)
+    }
+    return r
+}
+
+// A sandwich is broken once the cells between its solved 1 and largest
+// value add up to more than its sum, or all of them to anything else.
+func (s *sandwiches) Check(g *Grid) error {
+    for i, u := range s.units {
+        ends := []int{}
+        for j, p := range u.Cells {
+            if v := g.At(p); v == C(1) || v == C(g.values) {
+                ends = append(ends, j)
+            }
+        }
+        if len(ends) != 2 {
+            continue
+        }
+        total, solved := 0, 0
+        for _, p := range u.Cells[ends[0] + 1:ends[1]] {
+            if cell := g.At(p); cell.IsSolved() {
+                total += cell.Value()
+                solved++
+            }
+        }
+        if total > s.sums[i] || solved == ends[1] - ends[0] - 1 && total != s.sums[i] {
+            return &ContradictionError{u.Kind, u.Index, 0, fmt.Sprintf("the sandwich adds up to %d, not %d", total, s.sums[i])}
+        }
+    }
+    return nil
+}
+
+// Parse drawings written one to a line, as their String methods write
+// them: "thermo" and its cells from the bulb, "arrow" and its cells from
+// the circle, or "sandwich", a row or column like r3 or c2, and the sum.
+// Blank lines and lines starting with '#' are skipped.
+func ParseDrawings(input string) (Drawings, error) {
+    d := Drawings{}
+    found := false
+    for i, line := range strings.Split(input, "\n") {
+        fields := fieldsOf(line, i + 1)
+        if len(fields) == 0 || strings.HasPrefix(fields[0].text, "#") {
+            continue
+        }
+        found = true
+        kind := strings.ToLower(fields[0].text)
+        switch kind {
+            case "thermo", "arrow":
+                if len(fields) < 3 {
+                    return Drawings{}, &ParseError{i + 1, 0, fmt.Sprintf("expected %s and at least two cells", kind)}
+                }
+                cells := []Pos{}
+                for _, f := range fields[1:] {
+                    p, err := parsePos(f)
+                    if err != nil {
+                        return Drawings{}, err
+                    }
+                    cells = append(cells, p)
+                }
+                if kind == "thermo" {
+                    d.Thermos = append(d.Thermos, cells)
+                } else {
+                    d.Arrows = append(d.Arrows, Arrow{cells[0], cells[1:]})
+                }
+            case "sandwich":
+                if len(fields) != 3 {
+                    return Drawings{}, &ParseError{i + 1, 0, "expected sandwich, a row or column and a sum, like sandwich r3 15"}
+                }
+                s, err := parseSandwich(fields[1], fields[2])
+                if err != nil {
+                    return Drawings{}, err
+                }
+                d.Sandwiches = append(d.Sandwiches, s)
+            default:
+                return Drawings{}, &ParseError{i + 1, fields[0].col, fmt.Sprintf("expected thermo, arrow or sandwich, but found %q", fields[0].text)}
+        }
+    }
+    if !found {
+        return Drawings{}, &ParseError{1, 0, "no drawings found"}
+    }
+    return d, nil
+}
+
+// Read a sandwich's line, like r3 or c2, and its sum.
+func parseSandwich(line, sum field) (Sandwich, error) {
+    s := Sandwich{}
+    text := strings.ToLower(line.text)
+    index, err := strconv.Atoi(text[1:])
+    switch {
+        case err != nil || index < 1:
+            return s, &ParseError{line.line, line.col, fmt.Sprintf("expected a row or column like r3 or c2, but found %q", line.text)}
+        case text[0] == 'r':
+            s.Kind = Row
+        case text[0] == 'c':
+            s.Kind = Column
+        default:
+            return s, &ParseError{line.line, line.col, fmt.Sprintf("expected a row or column like r3 or c2, but found %q", line.text)}
+    }
+    s.Index = index - 1
+    if s.Sum, err = strconv.Atoi(sum.text); err != nil || s.Sum < 0 {
+        return s, &ParseError{sum.line, sum.col, fmt.Sprintf("%q is not a sum", sum.text)}
+    }
+    return s, nil
+}
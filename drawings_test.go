@@ -0,0 +1,167 @@
+package sudoku
+
+import (
+    "context"
+    "errors"
+    "io/ioutil"
+    "strings"
+    "testing"
+)
+
+// With the drawings of testdata/drawings.txt, the only filling of these
+// four givens is solved.
+const drawingsPuzzle = "...........2.....................3...........7............3......................"
+
+func readDrawings(t *testing.T) (string, Drawings) {
+    text, err := ioutil.ReadFile("testdata/drawings.txt")
+    if err != nil {
+        t.Fatalf("Could not read the drawings: %v", err)
+    }
+    d, err := ParseDrawings(string(text))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    return string(text), d
+}
+
+// The candidates p has left after the eliminations of r.
+func leftAfter(g *Grid, r Result, p Pos) Cell {
+    cell := g.At(p)
+    for _, e := range r.Eliminations {
+        if e.Pos == p {
+            cell = cell.difference(e.Values)
+        }
+    }
+    return cell
+}
+
+func TestDrawingsStringRoundTrips(t *testing.T) {
+    text, d := readDrawings(t)
+    // Everything but the comment on the first line.
+    expected := text[strings.Index(text, "\n") + 1:]
+    if d.String() != expected {
+        t.Errorf("Expected\n%s\nbut got\n%s", expected, d)
+    }
+    if len(d.Thermos) != 4 || len(d.Arrows) != 2 || len(d.Sandwiches) != 18 {
+        t.Errorf("Parsed the wrong drawings: %v", d)
+    }
+}
+
+func TestParseDrawingsSaysWhereTheInputIsWrong(t *testing.T) {
+    for input, expected := range map[string]string{
+        "thermo r1c1 r1c2\nkiller r1c1 r1c2": "sudoku: line 2, column 1: expected thermo, arrow or sandwich, but found \"killer\"",
+        "arrow r1c1": "sudoku: line 1: expected arrow and at least two cells",
+        "thermo r1c1 r1c2 rc3": "sudoku: line 1, column 18: expected a cell like r3c5, but found \"rc3\"",
+        "sandwich r3": "sudoku: line 1: expected sandwich, a row or column and a sum, like sandwich r3 15",
+        "sandwich b3 15": "sudoku: line 1, column 10: expected a row or column like r3 or c2, but found \"b3\"",
+        "sandwich c0 15": "sudoku: line 1, column 10: expected a row or column like r3 or c2, but found \"c0\"",
+        "sandwich c2 -1": "sudoku: line 1, column 13: \"-1\" is not a sum",
+        "# nothing": "sudoku: line 1: no drawings found",
+    } {
+        _, err := ParseDrawings(input)
+        if err == nil || err.Error() != expected {
+            t.Errorf("For %q expected %q, but got %v", input, expected, err)
+        }
+    }
+}
+
+func TestWithDrawingsRejectsBadDrawings(t *testing.T) {
+    for _, d := range []Drawings{
+        {Thermos: []Thermo{{{0, 0}, {0, 9}}}},
+        {Thermos: []Thermo{{{0, 0}, {0, 1}, {0, 0}}}},
+        {Thermos: []Thermo{{{0, 0}, {0, 1}, {0, 2}, {0, 3}, {0, 4}, {0, 5}, {0, 6}, {0, 7}, {0, 8}, {1, 8}}}},
+        {Arrows: []Arrow{{Circle: Pos{4, 4}}}},
+        {Arrows: []Arrow{{Pos{4, 4}, []Pos{{4, 5}, {4, 4}}}}},
+        {Sandwiches: []Sandwich{{Square, 0, 10}}},
+        {Sandwiches: []Sandwich{{Row, 9, 10}}},
+        {Sandwiches: []Sandwich{{Column, 0, 36}}},
+    } {
+        if _, err := StandardGeometry(9).WithDrawings(d); err == nil {
+            t.Errorf("Expected an error for %v", d)
+        }
+    }
+}
+
+func TestThermosBoundTheirCells(t *testing.T) {
+    geo, _ := StandardGeometry(9).WithDrawings(Drawings{Thermos: []Thermo{{{0, 0}, {1, 0}, {2, 0}}}})
+    g, _ := geo.NewGrid(NewBoard(9))
+    r := geo.Constraints[0].Apply(g)
+    for i, expected := range []Cell{C(1, 2, 3, 4, 5, 6, 7), C(2, 3, 4, 5, 6, 7, 8), C(3, 4, 5, 6, 7, 8, 9)} {
+        if left := leftAfter(g, r, Pos{i, 0}); left != expected {
+            t.Errorf("Expected r%dc1 to be left with %v, but got %v", i + 1, expected, left)
+        }
+    }
+}
+
+func TestArrowsBoundCircleAndPath(t *testing.T) {
+    geo, _ := StandardGeometry(9).WithDrawings(Drawings{Arrows: []Arrow{{Pos{4, 4}, []Pos{{0, 0}, {8, 8}}}}})
+    g, _ := geo.NewGrid(NewBoard(9))
+    r := geo.Constraints[0].Apply(g)
+    // Two cells add up to at least 2, and neither can be more than 8.
+    if left := leftAfter(g, r, Pos{4, 4}); left != C(2, 3, 4, 5, 6, 7, 8, 9) {
+        t.Errorf("Expected the circle to lose 1, but it has %v", left)
+    }
+    for _, p := range []Pos{{0, 0}, {8, 8}} {
+        if left := leftAfter(g, r, p); left != C(1, 2, 3, 4, 5, 6, 7, 8) {
+            t.Errorf("Expected %v to lose 9, but it has %v", p, left)
+        }
+    }
+}
+
+func TestSandwichesPlaceTheirCrusts(t *testing.T) {
+    // Only 2 to 8 add up to 35, so the 1 and 9 are at the ends of the row.
+    geo, _ := StandardGeometry(9).WithDrawings(Drawings{Sandwiches: []Sandwich{{Row, 0, 35}}})
+    g, _ := geo.NewGrid(NewBoard(9))
+    r := geo.Constraints[0].Apply(g)
+    for c := 0; c < 9; c++ {
+        expected := C(2, 3, 4, 5, 6, 7, 8)
+        if c == 0 || c == 8 {
+            expected = C(1, 9)
+        }
+        if left := leftAfter(g, r, Pos{0, c}); left != expected {
+            t.Errorf("Expected r1c%d to be left with %v, but got %v", c + 1, expected, left)
+        }
+    }
+}
+
+func TestReportsContradictionsInDrawings(t *testing.T) {
+    data := []struct {
+        drawings Drawings
+        cells    map[Pos]int
+        msg      string
+    }{
+        {Drawings{Thermos: []Thermo{{{0, 0}, {1, 0}, {2, 0}}}}, map[Pos]int{{0, 0}: 4, {2, 0}: 5}, "r3c1 can't be 5, 2 cells after a 4"},
+        {Drawings{Arrows: []Arrow{{Pos{4, 4}, []Pos{{0, 0}, {8, 8}}}}}, map[Pos]int{{4, 4}: 5, {0, 0}: 2, {8, 8}: 2}, "the path adds up to 4, not 5"},
+        {Drawings{Sandwiches: []Sandwich{{Row, 0, 10}}}, map[Pos]int{{0, 0}: 1, {0, 1}: 3, {0, 2}: 9}, "the sandwich adds up to 3, not 10"},
+    }
+    for _, datum := range data {
+        geo, _ := StandardGeometry(9).WithDrawings(datum.drawings)
+        board := NewBoard(9)
+        for p, v := range datum.cells {
+            board[p.Row][p.Col] = C(v)
+        }
+        _, err := geo.NewGrid(board)
+        if !errors.Is(err, ErrContradiction) || !strings.Contains(err.Error(), datum.msg) {
+            t.Errorf("Expected %q, but got %v", datum.msg, err)
+        }
+    }
+}
+
+func TestSolvesThermoArrowSandwich(t *testing.T) {
+    _, d := readDrawings(t)
+    geo, err := StandardGeometry(9).WithDrawings(d)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    puzzle, _ := Parse(drawingsPuzzle)
+    solution, err := geo.Solve(context.Background(), puzzle)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := solution.Equals(solved); !same {
+        t.Errorf("Unexpected solution: %v", msg)
+    }
+    if count := geo.CountSolutions(puzzle, 2); count != 1 {
+        t.Errorf("Expected 1 solution, but found %d", count)
+    }
+}
@@ -0,0 +1,77 @@
+package sudoku
+
+import (
+    "errors"
+    "fmt"
+)
+
+// The kinds of unit (row, column, square, diagonal, killer cage and so on)
+// a constraint can apply to. On a jigsaw board the squares are its
+// irregular regions. A pair is two cells a rule like anti-knight links,
+// thermos and arrows are the lines drawn on those puzzles, and a shaded
+// cell is one of an even/odd puzzle's, numbered in reading order.
+type UnitKind int
+
+const (
+    Row UnitKind = iota
+    Column
+    Square
+    DiagonalUnit
+    CageUnit
+    PairUnit
+    ThermoUnit
+    ArrowUnit
+    ShadedUnit
+)
+
+func (k UnitKind) String() string {
+    switch k {
+        case Row:
+            return "row"
+        case Column:
+            return "column"
+        case Square:
+            return "square"
+        case DiagonalUnit:
+            return "diagonal"
+        case CageUnit:
+            return "cage"
+        case PairUnit:
+            return "pair"
+        case ThermoUnit:
+            return "thermo"
+        case ArrowUnit:
+            return "arrow"
+        case ShadedUnit:
+            return "shaded cell"
+    }
+    return fmt.Sprintf("unit(%d)", int(k))
+}
+
+var (
+    // The board breaks the rules; see ContradictionError for where.
+    ErrContradiction = errors.New("sudoku: contradiction")
+    // Neither propagation nor search could complete the board, so the
+    // puzzle has no solution even though no single unit contradicts itself.
+    ErrNoProgress = errors.New("sudoku: no progress")
+    // The puzzle has more than one solution.
+    ErrMultipleSolutions = errors.New("sudoku: multiple solutions")
+)
+
+// A unit which cannot be completed, and the value that makes it so. A Value
+// of 0 means one of the unit's cells has no candidates left.
+type ContradictionError struct {
+    Unit  UnitKind
+    Index int
+    Value int
+    why   string
+}
+
+func (e *ContradictionError) Error() string {
+    return fmt.Sprintf("sudoku: contradiction in %v %d: %s", e.Unit, e.Index + 1, e.why)
+}
+
+// Lets errors.Is(err, ErrContradiction) match.
+func (e *ContradictionError) Unwrap() error {
+    return ErrContradiction
+}
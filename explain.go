@@ -0,0 +1,64 @@
+package sudoku
+
+import (
+    "context"
+)
+
+// How hard a guess is, on the same scale as the strategies.
+const guessDifficulty = 10.0
+
+// Solve the grid a step at a time with a Solver running every built-in
+// strategy, calling step with the deductions each one makes. When none of
+// them make progress, the most constrained cell's value is taken from the
+// solution as a guess; without a solution the walk stops there, as it does
+// if the grid turns out to be impossible.
+func walk(g *Grid, solution Board, step func(technique string, difficulty float64, deductions []Deduction)) {
+    solver := NewSolver(Strategies...)
+    for !g.solved() && g.conflict() == nil {
+        if strategy, r := solver.Step(g); strategy != nil {
+            deductions := r.Deductions
+            if len(deductions) == 0 {
+                deductions = []Deduction{{
+                    Technique: strategy.Name(),
+                    Placements: r.Placements,
+                    Eliminations: r.Eliminations,
+                }}
+            }
+            step(strategy.Name(), strategy.Difficulty(), deductions)
+            continue
+        }
+        if solution == nil {
+            return
+        }
+        row, col := g.fewestCandidates()
+        guess := Placement{Pos{row, col}, solution[row][col].Value()}
+        g.Apply(Result{Placements: []Placement{guess}})
+        step("Guess", guessDifficulty, []Deduction{{
+            Technique: "Guess",
+            Cells: []Pos{guess.Pos},
+            Values: C(guess.Value),
+            Placements: []Placement{guess},
+        }})
+    }
+}
+
+// Every deduction which solves the board, in the order a person would make
+// them: always the easiest one available. Where no technique applies, a
+// guess is taken from the solution. If the board has no solution, the
+// explanation stops where the techniques run out.
+func (input Board) Explain() []Deduction {
+    g, err := NewGrid(input)
+    if err != nil {
+        return nil
+    }
+    solution, err := input.SolveE(context.Background())
+    if err != nil && err != ErrMultipleSolutions {
+        solution = nil
+    }
+
+    trace := []Deduction{}
+    walk(g, solution, func(_ string, _ float64, deductions []Deduction) {
+        trace = append(trace, deductions...)
+    })
+    return trace
+}
@@ -0,0 +1,57 @@
+package sudoku
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestDeductionsExplainThemselves(t *testing.T) {
+    row := Unit{Row, 2, nil}
+    single := Deduction{
+        Technique: "Hidden single",
+        Unit: &row,
+        Cells: []Pos{{2, 4}},
+        Values: C(7),
+        Placements: []Placement{{Pos{2, 4}, 7}},
+    }
+    pair := Deduction{
+        Technique: "Naked pair",
+        Unit: &row,
+        Cells: []Pos{{2, 0}, {2, 1}},
+        Values: C(1,2),
+        Eliminations: []Elimination{{Pos{2, 5}, C(1,2)}, {Pos{2, 8}, C(2)}},
+    }
+
+    if s := single.String(); s != "Hidden single: 7 in row 3 can only go in r3c5" {
+        t.Errorf("Unexpected explanation %q", s)
+    }
+    if s := pair.String(); s != "Naked pair: 1, 2 in r3c1, r3c2 in row 3, so r3c6 can't be 1 or 2; r3c9 can't be 2" {
+        t.Errorf("Unexpected explanation %q", s)
+    }
+}
+
+func TestExplainTracesEveryStepOfTheSolution(t *testing.T) {
+    trace := unsolved.clone().Explain()
+    if len(trace) == 0 {
+        t.Fatalf("Expected some deductions")
+    }
+    if !strings.HasPrefix(trace[0].String(), "Hidden single: ") {
+        t.Errorf("Expected to start with a hidden single, but got %v", trace[0])
+    }
+
+    g, _ := NewGrid(unsolved)
+    for _, d := range trace {
+        g.Apply(Result{Placements: d.Placements, Eliminations: d.Eliminations})
+    }
+    if same, msg := g.Board.Equals(solved); !same {
+        t.Errorf("Replaying the deductions did not solve the board: %v", msg)
+    }
+}
+
+func TestExplainGuessesWhenItMust(t *testing.T) {
+    trace := NewBoard(4).Explain()
+
+    if len(trace) == 0 || trace[0].Technique != "Guess" {
+        t.Errorf("Expected an empty board to start with a guess, but got %v", trace)
+    }
+}
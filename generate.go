@@ -0,0 +1,250 @@
+package sudoku
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+)
+
+// Which cells a generated puzzle's clues are mirrored onto.
+type Symmetry int
+
+const (
+    NoSymmetry Symmetry = iota
+    // Half-turn rotation about the centre of the board.
+    Rotational
+    // Reflection in the main diagonal.
+    Diagonal
+    // Reflection left to right.
+    Mirror
+)
+
+func (s Symmetry) String() string {
+    switch s {
+        case NoSymmetry:
+            return "none"
+        case Rotational:
+            return "rotational"
+        case Diagonal:
+            return "diagonal"
+        case Mirror:
+            return "mirror"
+    }
+    return fmt.Sprintf("Symmetry(%d)", int(s))
+}
+
+// The cells which must be removed along with r,c to keep the symmetry.
+func (s Symmetry) partners(size, r, c int) []Pos {
+    cells := []Pos{{r, c}}
+    var other Pos
+    switch s {
+        case Rotational:
+            other = Pos{size - 1 - r, size - 1 - c}
+        case Diagonal:
+            other = Pos{c, r}
+        case Mirror:
+            other = Pos{r, size - 1 - c}
+        default:
+            return cells
+    }
+    if other != cells[0] {
+        cells = append(cells, other)
+    }
+    return cells
+}
+
+type GenerateOptions struct {
+    // Length of the board's sides; 0 means 9. Geometry, if set, decides it
+    // instead.
+    Size int
+    // The same seed (and options) always generates the same puzzle.
+    Seed int64
+    // Stop removing clues once this many are left; 0 removes all it can.
+    Clues int
+    Symmetry Symmetry
+    // Guarantee that every clue is needed for the solution to be unique.
+    // This takes priority over Clues and Symmetry: clues are removed past
+    // the target, and singly, until none can be.
+    Minimal bool
+    // The rows and columns of each box of an ordinary board, as in
+    // Boxes(BoxRows, BoxCols); 0 means the shape Board.BoxSize gives. Size
+    // may be left 0 when they are set.
+    BoxRows, BoxCols int
+    // The shape of the board, for variants like jigsaws or Sudoku-X; nil
+    // means an ordinary board of the given Size.
+    Geometry *Geometry
+}
+
+// Generate a puzzle with a unique solution, by filling a random board and
+// then taking away clues for as long as the solution stays unique.
+func Generate(opts GenerateOptions) (Board, error) {
+    geo, err := opts.geometry()
+    if err != nil {
+        return nil, err
+    }
+    rng := rand.New(rand.NewSource(opts.Seed))
+    puzzle := randomGrid(geo, rng)
+    if puzzle == nil {
+        return nil, errNoGrid
+    }
+    removeClues(geo, puzzle, opts, rng)
+    return puzzle, nil
+}
+
+// The markers GenerateAnnotated can draw on a puzzle.
+type Markers int
+
+const (
+    // Shade every cell even or odd.
+    ParityMarkers Markers = 1 << iota
+    // Put a sign between every two cells next to each other in a box.
+    InequalityMarkers
+)
+
+// Generate an even/odd or greater-than puzzle, as Generate does, with the
+// markers taken from its solution before any clues are taken away. Far
+// fewer clues are left than on an ordinary puzzle; often none at all.
+func GenerateAnnotated(opts GenerateOptions, markers Markers) (Board, Annotations, error) {
+    geo, err := opts.geometry()
+    if err != nil {
+        return nil, Annotations{}, err
+    }
+    rng := rand.New(rand.NewSource(opts.Seed))
+    puzzle := randomGrid(geo, rng)
+    if puzzle == nil {
+        return nil, Annotations{}, errNoGrid
+    }
+    notes := Annotations{}
+    if markers & ParityMarkers != 0 {
+        for r, row := range puzzle {
+            for c, cell := range row {
+                if cell.Value() % 2 == 0 {
+                    notes.Even = append(notes.Even, Pos{r, c})
+                } else {
+                    notes.Odd = append(notes.Odd, Pos{r, c})
+                }
+            }
+        }
+    }
+    if markers & InequalityMarkers != 0 {
+        box := map[Pos]int{}
+        for i, u := range geo.Units {
+            if u.Kind == Square {
+                for _, p := range u.Cells {
+                    box[p] = i + 1
+                }
+            }
+        }
+        for _, pair := range geo.pairsOf(Orthogonal) {
+            a, b := pair[0], pair[1]
+            if box[a] == 0 || box[a] != box[b] {
+                continue
+            }
+            relation := LessThan
+            if puzzle[a.Row][a.Col].Value() > puzzle[b.Row][b.Col].Value() {
+                relation = GreaterThan
+            }
+            notes.Inequalities = append(notes.Inequalities, Marking{relation, pair})
+        }
+    }
+    if geo, err = geo.WithAnnotations(notes); err != nil {
+        return nil, Annotations{}, err
+    }
+    removeClues(geo, puzzle, opts, rng)
+    return puzzle, notes, nil
+}
+
+// The geometry to generate a puzzle in.
+func (opts GenerateOptions) geometry() (*Geometry, error) {
+    if opts.Geometry != nil {
+        return opts.Geometry, nil
+    }
+    size := opts.Size
+    if opts.BoxRows != 0 || opts.BoxCols != 0 {
+        if opts.BoxRows < 1 || opts.BoxCols < 1 || size != 0 && size != opts.BoxRows * opts.BoxCols {
+            return nil, fmt.Errorf("sudoku: %v-by-%v boxes don't fit a %v-by-%v board", opts.BoxRows, opts.BoxCols, size, size)
+        }
+        return NewGeometry(Boxes(opts.BoxRows, opts.BoxCols))
+    }
+    if size == 0 {
+        size = 9
+    }
+    geo := StandardGeometry(size)
+    for _, u := range geo.Units {
+        if u.Kind == Square {
+            return geo, nil
+        }
+    }
+    return nil, fmt.Errorf("sudoku: cannot generate a %v-by-%v board, it has no boxes", size, size)
+}
+
+// Take clues away from a solved board for as long as the solution stays
+// unique, as the options say.
+func removeClues(geo *Geometry, puzzle Board, opts GenerateOptions, rng *rand.Rand) {
+    size := geo.Size
+    // Only cells in a unit are part of the puzzle: the gaps between a
+    // Samurai's grids aren't.
+    inPuzzle := map[Pos]bool{}
+    for _, u := range geo.Units {
+        for _, p := range u.Cells {
+            inPuzzle[p] = true
+        }
+    }
+    clues := len(inPuzzle)
+
+    // Take away the clues in the given cells, putting them back if that
+    // leaves more than one solution.
+    remove := func(cells []Pos) {
+        kept := make([]Cell, len(cells))
+        for i, p := range cells {
+            kept[i] = puzzle[p.Row][p.Col]
+            puzzle[p.Row][p.Col] = C()
+        }
+        if geo.CountSolutions(puzzle, 2) == 1 {
+            clues -= len(cells)
+            return
+        }
+        for i, p := range cells {
+            puzzle[p.Row][p.Col] = kept[i]
+        }
+    }
+
+    for _, i := range rng.Perm(size * size) {
+        if !inPuzzle[Pos{i / size, i % size}] || puzzle[i / size][i % size].isEmpty() {
+            continue
+        }
+        cells := []Pos{}
+        for _, p := range opts.Symmetry.partners(size, i / size, i % size) {
+            if inPuzzle[p] {
+                cells = append(cells, p)
+            }
+        }
+        if clues - len(cells) >= opts.Clues {
+            remove(cells)
+        }
+    }
+    // Removing clues never makes a solution unique, so one more pass is
+    // enough to be sure that none of those left can go.
+    if opts.Minimal {
+        for _, i := range rng.Perm(size * size) {
+            if inPuzzle[Pos{i / size, i % size}] && !puzzle[i / size][i % size].isEmpty() {
+                remove([]Pos{{i / size, i % size}})
+            }
+        }
+    }
+}
+
+// Returned when no board of the geometry can be filled in, as when its
+// rules contradict each other.
+var errNoGrid = errors.New("sudoku: no board of the geometry keeps to all its rules")
+
+// A random solved board of the geometry, or nil if there is none.
+func randomGrid(geo *Geometry, rng *rand.Rand) Board {
+    var grid Board
+    geo.solver().each(context.Background(), NewBoard(geo.Size), rng, func(b Board) bool {
+        grid = b
+        return false
+    })
+    return grid
+}
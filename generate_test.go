@@ -0,0 +1,167 @@
+package sudoku
+
+import (
+    "testing"
+)
+
+func clueCount(board Board) int {
+    clues := 0
+    for i := range board {
+        for j := range board[i] {
+            if !board[i][j].isEmpty() {
+                clues++
+            }
+        }
+    }
+    return clues
+}
+
+func TestGeneratesAUniquePuzzle(t *testing.T) {
+    puzzle, err := Generate(GenerateOptions{Seed: 1})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if count := puzzle.CountSolutions(2); count != 1 {
+        t.Errorf("Expected a unique solution, but found %v for\n%#v", count, puzzle)
+    }
+    if puzzle.Solve() == nil {
+        t.Errorf("Solve() could not solve the generated puzzle\n%#v", puzzle)
+    }
+}
+
+func TestGenerateIsDeterministicForASeed(t *testing.T) {
+    first, _ := Generate(GenerateOptions{Seed: 42})
+    second, _ := Generate(GenerateOptions{Seed: 42})
+    other, _ := Generate(GenerateOptions{Seed: 43})
+
+    if same, msg := first.Equals(second); !same {
+        t.Errorf("Expected the same puzzle for the same seed, but got %v", msg)
+    }
+    if same, _ := first.Equals(other); same {
+        t.Errorf("Expected a different puzzle for a different seed")
+    }
+}
+
+func TestGenerateStopsAtTheClueTarget(t *testing.T) {
+    puzzle, _ := Generate(GenerateOptions{Seed: 2, Clues: 40})
+
+    if clues := clueCount(puzzle); clues < 40 || clues > 41 {
+        t.Errorf("Expected 40 clues, but there were %v", clues)
+    }
+}
+
+func TestGenerateKeepsTheSymmetry(t *testing.T) {
+    for _, symmetry := range []Symmetry{Rotational, Diagonal, Mirror} {
+        puzzle, _ := Generate(GenerateOptions{Seed: 3, Symmetry: symmetry})
+        for i := range puzzle {
+            for j := range puzzle[i] {
+                for _, partner := range symmetry.partners(9, i, j) {
+                    if puzzle[i][j].isEmpty() != puzzle[partner.Row][partner.Col].isEmpty() {
+                        t.Errorf("%v symmetry broken at %v,%v and %v", symmetry, i, j, partner)
+                    }
+                }
+            }
+        }
+    }
+}
+
+func TestGeneratesMinimalPuzzles(t *testing.T) {
+    puzzle, _ := Generate(GenerateOptions{Seed: 4, Clues: 30, Symmetry: Rotational, Minimal: true})
+
+    for i := range puzzle {
+        for j := range puzzle[i] {
+            if puzzle[i][j].isEmpty() {
+                continue
+            }
+            fewer := puzzle.clone()
+            fewer[i][j] = C()
+            if fewer.CountSolutions(2) == 1 {
+                t.Errorf("The clue at %v,%v is not needed", i, j)
+            }
+        }
+    }
+}
+
+func TestGeneratesOtherSizes(t *testing.T) {
+    puzzle, err := Generate(GenerateOptions{Size: 4, Seed: 5})
+    if err != nil || len(puzzle) != 4 || puzzle.CountSolutions(2) != 1 {
+        t.Errorf("Expected a unique 4x4 puzzle, but got %v (%v)", puzzle, err)
+    }
+    if _, err := Generate(GenerateOptions{Size: 5}); err == nil {
+        t.Errorf("Expected an error for a board with no boxes")
+    }
+}
+
+func TestGeneratesBoardsWithRectangularBoxes(t *testing.T) {
+    for _, size := range []int{6, 8} {
+        puzzle, err := Generate(GenerateOptions{Size: size, Seed: 3})
+        if err != nil {
+            t.Fatalf("Unexpected error %v", err)
+        }
+        if len(puzzle) != size || puzzle.CountSolutions(2) != 1 {
+            t.Errorf("Expected a unique %v-by-%v puzzle, but got\n%#v", size, size, puzzle)
+        }
+    }
+    if _, err := Generate(GenerateOptions{Size: 7}); err == nil {
+        t.Errorf("Expected an error for a board with no boxes")
+    }
+    // Boxes standing on end, rather than the 2x3 ones of a 6x6 board.
+    standing, _ := NewGeometry(Boxes(3, 2))
+    puzzle, err := Generate(GenerateOptions{Geometry: standing, Seed: 3})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if standing.CountSolutions(puzzle, 2) != 1 {
+        t.Errorf("Expected a unique puzzle with 3x2 boxes, but got\n%v", standing.Draw(puzzle))
+    }
+    puzzle, err = Generate(GenerateOptions{BoxRows: 3, BoxCols: 2, Seed: 3})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if len(puzzle) != 6 || standing.CountSolutions(puzzle, 2) != 1 {
+        t.Errorf("Expected a unique 6x6 puzzle with 3x2 boxes, but got\n%v", standing.Draw(puzzle))
+    }
+    if _, err := Generate(GenerateOptions{Size: 9, BoxRows: 3, BoxCols: 2}); err == nil {
+        t.Errorf("Expected an error for boxes that don't fit the board")
+    }
+}
+
+func TestGenerateCountsOnlyThePuzzlesCells(t *testing.T) {
+    // A Samurai has 369 cells; the rest of its 21x21 board is gaps.
+    puzzle, err := Generate(GenerateOptions{Geometry: SamuraiGeometry(), Seed: 4, Clues: 300})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if clues := clueCount(puzzle); clues < 300 || clues > 301 {
+        t.Errorf("Expected 300 clues, but there were %v", clues)
+    }
+}
+
+func TestGenerateRejectsGeometriesWithNoSolution(t *testing.T) {
+    // No two values of a 4x4 board add up to 10.
+    geo, _ := StandardGeometry(4).WithMarkings(Marking{XSum, [2]Pos{{0, 0}, {0, 1}}})
+    if puzzle, err := Generate(GenerateOptions{Geometry: geo}); err == nil {
+        t.Errorf("Expected an error, but got %v", puzzle)
+    }
+    if puzzle, _, err := GenerateAnnotated(GenerateOptions{Geometry: geo}, ParityMarkers); err == nil {
+        t.Errorf("Expected an error, but got %v", puzzle)
+    }
+}
+
+func TestGeneratesAnnotatedPuzzles(t *testing.T) {
+    puzzle, notes, err := GenerateAnnotated(GenerateOptions{Seed: 1}, ParityMarkers | InequalityMarkers)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    // Every cell is shaded, and there are 12 signs in each box.
+    if len(notes.Even) + len(notes.Odd) != 81 || len(notes.Inequalities) != 108 {
+        t.Errorf("Expected every marker, but got %v", notes)
+    }
+    geo, _ := StandardGeometry(9).WithAnnotations(notes)
+    if count := geo.CountSolutions(puzzle, 2); count != 1 {
+        t.Errorf("Expected a unique solution, but found %v for\n%s", count, Digits.FormatAnnotated(puzzle, notes))
+    }
+    if ordinary, _ := Generate(GenerateOptions{Seed: 1}); clueCount(puzzle) >= clueCount(ordinary) {
+        t.Errorf("Expected fewer clues than on an ordinary puzzle, but got %d", clueCount(puzzle))
+    }
+}
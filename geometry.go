@@ -0,0 +1,326 @@
+package sudoku
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// The region each cell of a board belongs to, numbered from 0. Each
+// region must hold every value once, as the squares of an ordinary board
+// do; jigsaw puzzles have irregular ones.
+type Layout [][]int
+
+// The ordinary layout of a size-by-size board, whose regions are boxes of
+// the shape Board.BoxSize gives, numbered across then down.
+func BoxLayout(size int) (Layout, error) {
+    rows, cols := boxShape(size)
+    if rows == 0 {
+        return nil, fmt.Errorf("sudoku: %d-by-%d boards have no boxes", size, size)
+    }
+    return Boxes(rows, cols), nil
+}
+
+// The layout of a board whose regions are boxes rows tall and cols wide,
+// so rows * cols cells on a side: Boxes(3, 2) is a 6x6 board of boxes
+// standing on end.
+func Boxes(rows, cols int) Layout {
+    size := rows * cols
+    layout := make(Layout, size)
+    for r := range layout {
+        layout[r] = make([]int, size)
+        for c := range layout[r] {
+            layout[r][c] = r / rows * rows + c / cols
+        }
+    }
+    return layout
+}
+
+// Parse a layout written as a grid of letters, or any other characters,
+// one per cell, with the same character for every cell of a region.
+// Regions are numbered in the order they are first seen. The grid is laid
+// out as for Parse.
+func ParseLayout(input string) (Layout, error) {
+    rows := [][]symbol{}
+    for i, line := range strings.Split(input, "\n") {
+        line = strings.TrimRight(line, "\r")
+        if isSeparator(line) {
+            continue
+        }
+        row := []symbol{}
+        for j, r := range []rune(line) {
+            if !isDecoration(r) {
+                row = append(row, symbol{r, i + 1, j + 1})
+            }
+        }
+        if len(row) > 0 {
+            rows = append(rows, row)
+        }
+    }
+    if len(rows) == 0 {
+        return nil, &ParseError{1, 0, "no layout found"}
+    }
+
+    size := len(rows)
+    layout := make(Layout, size)
+    ids := map[rune]int{}
+    firsts := []symbol{}
+    counts := []int{}
+    for i, row := range rows {
+        if len(row) != size {
+            return nil, &ParseError{row[0].line, 0, fmt.Sprintf("expected %d cells in the row, but found %d", size, len(row))}
+        }
+        layout[i] = make([]int, size)
+        for j, s := range row {
+            id, seen := ids[s.r]
+            if !seen {
+                if len(ids) == size {
+                    return nil, &ParseError{s.line, s.col, fmt.Sprintf("%q makes more than %d regions", s.r, size)}
+                }
+                id = len(ids)
+                ids[s.r] = id
+                firsts = append(firsts, s)
+                counts = append(counts, 0)
+            }
+            layout[i][j] = id
+            counts[id]++
+        }
+    }
+    for id, count := range counts {
+        if count != size {
+            s := firsts[id]
+            return nil, &ParseError{s.line, s.col, fmt.Sprintf("region %q has %d cells, but needs %d", s.r, count, size)}
+        }
+    }
+    return layout, nil
+}
+
+// The cells of each region, in reading order. Returns an error unless the
+// layout is square, with as many regions as rows and as many cells in
+// each region as in a row.
+func (l Layout) regions() ([][]Pos, error) {
+    size := len(l)
+    regions := make([][]Pos, size)
+    for r := range l {
+        if len(l[r]) != size {
+            return nil, fmt.Errorf("sudoku: row %d of the layout has %d cells, but needs %d", r + 1, len(l[r]), size)
+        }
+        for c, id := range l[r] {
+            if id < 0 || id >= size {
+                return nil, fmt.Errorf("sudoku: region %d at %v is out of range", id, Pos{r, c})
+            }
+            regions[id] = append(regions[id], Pos{r, c})
+        }
+    }
+    for id, cells := range regions {
+        if len(cells) != size {
+            return nil, fmt.Errorf("sudoku: region %d has %d cells, but needs %d", id, len(cells), size)
+        }
+    }
+    return regions, nil
+}
+
+// The shape of a puzzle: the size of its board, the units whose cells
+// must each hold every value once, and any other rules its cells follow.
+type Geometry struct {
+    Size        int
+    Units       []Unit
+    Constraints []Constraint
+    // How many values each unit holds; 0 means Size.
+    Values      int
+}
+
+func (geo *Geometry) values() int {
+    if geo.Values == 0 {
+        return geo.Size
+    }
+    return geo.Values
+}
+
+// The geometry of a board with the rows, columns and regions of the layout.
+func NewGeometry(layout Layout) (*Geometry, error) {
+    regions, err := layout.regions()
+    if err != nil {
+        return nil, err
+    }
+    geo := linesOf(len(layout))
+    for i, cells := range regions {
+        geo.Units = append(geo.Units, Unit{Square, i, cells})
+    }
+    return geo, nil
+}
+
+// Just the rows and columns of a size-by-size board.
+func linesOf(size int) *Geometry {
+    geo := &Geometry{Size: size, Units: make([]Unit, 0, 3 * size)}
+    for _, kind := range []UnitKind{Row, Column} {
+        for i := 0; i < size; i++ {
+            u := Unit{kind, i, make([]Pos, size)}
+            for j := range u.Cells {
+                if kind == Row {
+                    u.Cells[j] = Pos{i, j}
+                } else {
+                    u.Cells[j] = Pos{j, i}
+                }
+            }
+            geo.Units = append(geo.Units, u)
+        }
+    }
+    return geo
+}
+
+// The geometry of an ordinary size-by-size board. Sizes which can't be
+// split into boxes have only rows and columns.
+func StandardGeometry(size int) *Geometry {
+    layout, err := BoxLayout(size)
+    if err != nil {
+        return linesOf(size)
+    }
+    geo, _ := NewGeometry(layout)
+    return geo
+}
+
+// A copy of the geometry in which both main diagonals must also hold every
+// value once, as in Sudoku-X. The diagonal from the top left is Index 0.
+func (geo *Geometry) WithDiagonals() *Geometry {
+    out := *geo
+    out.Units = append([]Unit{}, geo.Units...)
+    down := Unit{DiagonalUnit, 0, make([]Pos, geo.Size)}
+    up := Unit{DiagonalUnit, 1, make([]Pos, geo.Size)}
+    for i := 0; i < geo.Size; i++ {
+        down.Cells[i] = Pos{i, i}
+        up.Cells[i] = Pos{i, geo.Size - 1 - i}
+    }
+    out.Units = append(out.Units, down, up)
+    return &out
+}
+
+// Like Board.GoString, but with the cells of any diagonals marked by a
+// '*', and any shading and signs drawn as Alphabet.Draw draws them.
+func (geo *Geometry) Draw(board Board) string {
+    return Digits.Draw(board, geo)
+}
+
+// The cells on the geometry's diagonals, or nil if it has none.
+func (geo *Geometry) diagonalCells() map[Pos]bool {
+    if geo == nil {
+        return nil
+    }
+    var diagonal map[Pos]bool
+    for _, u := range geo.Units {
+        if u.Kind == DiagonalUnit {
+            if diagonal == nil {
+                diagonal = map[Pos]bool{}
+            }
+            for _, p := range u.Cells {
+                diagonal[p] = true
+            }
+        }
+    }
+    return diagonal
+}
+
+// The box, or other region, of each cell: numbered by the geometry's
+// squares, or by the boxes Board.BoxSize gives if geo is nil. Cells in no
+// region are in region -1.
+func (geo *Geometry) regionOf(size int) func(Pos) int {
+    if geo == nil {
+        rows, cols := boxShape(size)
+        if rows == 0 {
+            return func(Pos) int { return 0 }
+        }
+        return func(p Pos) int { return p.Row / rows * rows + p.Col / cols }
+    }
+    region := map[Pos]int{}
+    for _, u := range geo.Units {
+        if u.Kind == Square {
+            for _, p := range u.Cells {
+                region[p] = u.Index
+            }
+        }
+    }
+    return func(p Pos) int {
+        if r, ok := region[p]; ok {
+            return r
+        }
+        return -1
+    }
+}
+
+// A copy of the geometry with more constraints, kept easiest first.
+func (geo *Geometry) with(constraints ...Constraint) *Geometry {
+    out := *geo
+    out.Constraints = append(append([]Constraint{}, geo.Constraints...), constraints...)
+    sort.SliceStable(out.Constraints, func(i, j int) bool {
+        return out.Constraints[i].Difficulty() < out.Constraints[j].Difficulty()
+    })
+    return &out
+}
+
+// Apply the filter to every unit of the geometry in turn, returning the
+// result. Board.Step is this with the board's StandardGeometry.
+func (geo *Geometry) Step(input Board, filter func(Set) Set) Board {
+    board := input.clone()
+    for _, u := range geo.Units {
+        u.Cells = onBoard(board, u.Cells)
+        updated := filter(geo.setOf(board, u))
+        for i, p := range u.Cells {
+            if i < len(updated) {
+                board[p.Row][p.Col] = updated[i]
+            }
+        }
+    }
+    return board
+}
+
+// The cells which are on the board: all of them, unless some of its rows
+// are short, as those of boards built by hand can be.
+func onBoard(board Board, cells []Pos) []Pos {
+    on := func(p Pos) bool {
+        return p.Row < len(board) && p.Col < len(board[p.Row])
+    }
+    for i, p := range cells {
+        if !on(p) {
+            kept := append([]Pos{}, cells[:i]...)
+            for _, q := range cells[i + 1:] {
+                if on(q) {
+                    kept = append(kept, q)
+                }
+            }
+            return kept
+        }
+    }
+    return cells
+}
+
+func (geo *Geometry) setOf(board Board, u Unit) Set {
+    set := make(Set, len(u.Cells))
+    for i, p := range u.Cells {
+        set[i] = board[p.Row][p.Col]
+    }
+    return set
+}
+
+// A solver like the one Board.SolveE uses, for boards of this geometry.
+func (geo *Geometry) solver() *Solver {
+    s := *defaultSolver
+    s.Geometry = geo
+    return &s
+}
+
+// Solve a board of this geometry, as Board.SolveE does for ordinary ones.
+func (geo *Geometry) Solve(ctx context.Context, board Board) (Board, error) {
+    return geo.solver().Solve(ctx, board)
+}
+
+// Count the solutions of a board of this geometry, as Board.CountSolutions
+// does for ordinary ones.
+func (geo *Geometry) CountSolutions(board Board, limit int) int {
+    count := 0
+    geo.solver().each(context.Background(), board, nil, func(Board) bool {
+        count++
+        return limit <= 0 || count < limit
+    })
+    return count
+}
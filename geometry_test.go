@@ -0,0 +1,223 @@
+package sudoku
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+)
+
+// solved, with 5s swapped between the first two squares and 3s between
+// the fifth and sixth, still fits this.
+const jigsaw = `
+AAABBBCCC
+AAAABBCCC
+AABBBBCCC
+DDDEEEEFF
+DDDEEFFFF
+DDDEEEFFF
+GGGHHHIII
+GGGHHHIII
+GGGHHHIII
+`
+
+func jigsawGeometry(t *testing.T) *Geometry {
+    layout, err := ParseLayout(jigsaw)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    geo, err := NewGeometry(layout)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    return geo
+}
+
+func TestParsesALayoutOfLetters(t *testing.T) {
+    layout, err := ParseLayout(jigsaw)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if len(layout) != 9 || layout[2][2] != 1 || layout[1][3] != 0 || layout[4][5] != 5 || layout[8][8] != 8 {
+        t.Errorf("Parsed the wrong layout %v", layout)
+    }
+}
+
+func TestBoxLayoutMatchesTheSquares(t *testing.T) {
+    layout, err := BoxLayout(9)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if layout[0][0] != 0 || layout[2][3] != 1 || layout[4][4] != 4 || layout[8][6] != 8 {
+        t.Errorf("Wrong layout %v", layout)
+    }
+    if _, err := BoxLayout(7); err == nil {
+        t.Errorf("Expected an error for a board with no boxes")
+    }
+}
+
+func TestBoxesCanStandOnEnd(t *testing.T) {
+    layout := Boxes(3, 2)
+    if len(layout) != 6 || layout[2][1] != 0 || layout[0][2] != 1 || layout[3][0] != 3 || layout[5][5] != 5 {
+        t.Errorf("Wrong layout %v", layout)
+    }
+    if _, err := NewGeometry(layout); err != nil {
+        t.Errorf("Unexpected error %v", err)
+    }
+}
+
+func TestDrawMarksOutTheGeometrysBoxes(t *testing.T) {
+    standing, _ := NewGeometry(Boxes(3, 2))
+    rows := strings.Repeat("   |   |   |\n", 3) + "------------\n"
+    if drawn := standing.Draw(NewBoard(6)); drawn != rows + rows {
+        t.Errorf("Expected\n%v\nbut got\n%v", rows + rows, drawn)
+    }
+    layout, _ := ParseLayout("AABB\nACBB\nACCD\nCDDD")
+    jigsaw, _ := NewGeometry(layout)
+    expected := "   |   |\n  --\n | |   |\n    ----\n |   | |\n------\n |     |\n--------\n"
+    if drawn := jigsaw.Draw(NewBoard(4)); drawn != expected {
+        t.Errorf("Expected\n%v\nbut got\n%v", expected, drawn)
+    }
+}
+
+func TestParseLayoutSaysWhereTheInputIsWrong(t *testing.T) {
+    for input, expected := range map[string]string{
+        "AB\nBAA": "sudoku: line 2: expected 2 cells in the row, but found 3",
+        "AB\nCA": "sudoku: line 2, column 1: 'C' makes more than 2 regions",
+        "AA\nAB": "sudoku: line 1, column 1: region 'A' has 3 cells, but needs 2",
+    } {
+        _, err := ParseLayout(input)
+        if err == nil || err.Error() != expected {
+            t.Errorf("For %q expected %q, but got %v", input, expected, err)
+        }
+    }
+}
+
+func TestNewGeometryRejectsBadLayouts(t *testing.T) {
+    for _, layout := range []Layout{
+        {{0, 0}, {1}},
+        {{0, 2}, {1, 1}},
+        {{0, 0}, {0, 1}},
+    } {
+        if _, err := NewGeometry(layout); err == nil {
+            t.Errorf("Expected an error for %v", layout)
+        }
+    }
+}
+
+func TestSolvesJigsaws(t *testing.T) {
+    geo := jigsawGeometry(t)
+
+    full := solved
+    for _, u := range geo.Units {
+        if findMissingValues(geo.setOf(full, u)) != 0 {
+            t.Fatalf("%v is not complete in %v", u, full)
+        }
+    }
+
+    puzzle := full.clone()
+    for r := range puzzle {
+        for c := range puzzle[r] {
+            cell := puzzle[r][c]
+            puzzle[r][c] = C()
+            if geo.CountSolutions(puzzle, 2) != 1 {
+                puzzle[r][c] = cell
+            }
+        }
+    }
+    solution, err := geo.Solve(context.Background(), puzzle)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := solution.Equals(full); !same {
+        t.Errorf("Solved %v wrongly: %v", puzzle, msg)
+    }
+}
+
+func TestGeometryStepFiltersTheRegions(t *testing.T) {
+    geo := jigsawGeometry(t)
+    board := NewBoard(9)
+    for i := range board {
+        for j := range board[i] {
+            board[i][j] = C(i * 9 + j + 1)
+        }
+    }
+
+    sets := []string{}
+    geo.Step(board, func(set Set) Set {
+        values := []string{}
+        for _, cell := range set {
+            values = append(values, cell.String())
+        }
+        sets = append(sets, strings.Join(values, ""))
+        return set
+    })
+    if len(sets) != 27 || sets[18] != "[1][2][3][10][11][12][13][19][20]" {
+        t.Errorf("Expected the first region to be filtered, but got %v", sets)
+    }
+}
+
+func TestNewGridChecksTheBoardFitsTheGeometry(t *testing.T) {
+    if _, err := jigsawGeometry(t).NewGrid(NewBoard(4)); err == nil {
+        t.Errorf("Expected an error for a 4-by-4 board")
+    }
+}
+
+func TestDiagonalsAreTwoMoreUnits(t *testing.T) {
+    geo := StandardGeometry(9).WithDiagonals()
+    if len(geo.Units) != 29 || len(StandardGeometry(9).Units) != 27 {
+        t.Fatalf("Expected 29 units, but got %v", len(geo.Units))
+    }
+    down, up := geo.Units[27], geo.Units[28]
+    if down.String() != "diagonal 1" || down.Cells[4] != (Pos{4, 4}) || up.Cells[0] != (Pos{0, 8}) || up.Cells[8] != (Pos{8, 0}) {
+        t.Errorf("Wrong diagonals %v and %v", down, up)
+    }
+
+    calls := 0
+    geo.Step(NewBoard(9), func(set Set) Set {
+        calls++
+        return ConstrainSet(set)
+    })
+    if calls != 29 {
+        t.Errorf("Expected the filter to be called for all 29 units, but got %v", calls)
+    }
+}
+
+func TestSolvesAndGeneratesSudokuX(t *testing.T) {
+    geo := StandardGeometry(9).WithDiagonals()
+    puzzle, err := Generate(GenerateOptions{Seed: 5, Geometry: geo})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    solution, err := geo.Solve(context.Background(), puzzle)
+    if err != nil {
+        t.Fatalf("Unexpected error %v for\n%v", err, geo.Draw(puzzle))
+    }
+    for _, u := range geo.Units {
+        if findMissingValues(geo.setOf(solution, u)) != 0 {
+            t.Errorf("%v is not complete in %v", u, solution)
+        }
+    }
+}
+
+func TestReportsContradictionsOnTheDiagonals(t *testing.T) {
+    board := NewBoard(4)
+    board[0][0], board[3][3] = C(1), C(1)
+    _, err := StandardGeometry(4).WithDiagonals().Solve(context.Background(), board)
+    var contradiction *ContradictionError
+    if !errors.As(err, &contradiction) || contradiction.Unit != DiagonalUnit || contradiction.Index != 0 {
+        t.Errorf("Expected a contradiction in the first diagonal, but got %v", err)
+    }
+}
+
+func TestDrawMarksTheDiagonals(t *testing.T) {
+    board, _ := Parse("1234341221434321")
+    expected := "*1  2| 3 *4|\n 3 *4|*1  2|\n------------\n" +
+        " 2 *1|*4  3|\n*4  3| 2 *1|\n------------\n"
+    if drawn := StandardGeometry(4).WithDiagonals().Draw(board); drawn != expected {
+        t.Errorf("Expected\n%v\nbut got\n%v", expected, drawn)
+    }
+    if drawn := StandardGeometry(4).Draw(board); drawn != board.GoString() {
+        t.Errorf("Expected no marks without diagonals, but got\n%v", drawn)
+    }
+}
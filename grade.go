@@ -0,0 +1,107 @@
+package sudoku
+
+import (
+    "context"
+    "fmt"
+    "sort"
+)
+
+// How hard a puzzle is to solve without guessing.
+type Tier int
+
+const (
+    Easy Tier = iota
+    Medium
+    Hard
+    Fiendish
+    Diabolical
+)
+
+func (t Tier) String() string {
+    switch t {
+        case Easy:
+            return "easy"
+        case Medium:
+            return "medium"
+        case Hard:
+            return "hard"
+        case Fiendish:
+            return "fiendish"
+        case Diabolical:
+            return "diabolical"
+    }
+    return fmt.Sprintf("Tier(%d)", int(t))
+}
+
+// The tier for a rating: singles are easy, locked candidates medium,
+// subsets and small fish hard, wings and chains fiendish, and anything
+// which has to guess diabolical.
+func tierFor(rating float64) Tier {
+    switch {
+        case rating <= 2.3:
+            return Easy
+        case rating <= 2.8:
+            return Medium
+        case rating <= 4.0:
+            return Hard
+        case rating < 10:
+            return Fiendish
+    }
+    return Diabolical
+}
+
+// How many deductions of a technique were needed while grading.
+type TechniqueUse struct {
+    Technique  string
+    Difficulty float64
+    Count      int
+}
+
+// A puzzle's difficulty: the rating of the hardest technique needed to
+// solve it, its tier, and every technique used along the way, easiest first.
+type Grade struct {
+    Rating float64
+    Tier   Tier
+    Used   []TechniqueUse
+}
+
+// Grade the puzzle by solving it the way a person would, as Explain does.
+// Puzzles without exactly one solution can't be graded; the error is as
+// for SolveE.
+func (input Board) Grade() (Grade, error) {
+    return StandardGeometry(len(input)).Grade(input)
+}
+
+// Grade a board of this geometry, as Board.Grade does for ordinary ones.
+// Deductions from the geometry's constraints count as techniques too.
+func (geo *Geometry) Grade(input Board) (Grade, error) {
+    solution, err := geo.Solve(context.Background(), input)
+    if err != nil {
+        return Grade{}, err
+    }
+    g, err := geo.NewGrid(input)
+    if err != nil {
+        return Grade{}, err
+    }
+
+    counts := make(map[string]int)
+    grade := Grade{}
+    walk(g, solution, func(technique string, difficulty float64, deductions []Deduction) {
+        if counts[technique] == 0 {
+            grade.Used = append(grade.Used, TechniqueUse{technique, difficulty, 0})
+        }
+        counts[technique] += len(deductions)
+        if difficulty > grade.Rating {
+            grade.Rating = difficulty
+        }
+    })
+
+    for i := range grade.Used {
+        grade.Used[i].Count = counts[grade.Used[i].Technique]
+    }
+    sort.SliceStable(grade.Used, func(i, j int) bool {
+        return grade.Used[i].Difficulty < grade.Used[j].Difficulty
+    })
+    grade.Tier = tierFor(grade.Rating)
+    return grade, nil
+}
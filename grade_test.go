@@ -0,0 +1,244 @@
+package sudoku
+
+import (
+    "context"
+    "strings"
+    "testing"
+)
+
+// A grid whose first row holds the given candidates, and whose other cells could hold anything.
+func gridWithRow(row Set) *Grid {
+    board := NewBoard(len(row))
+    board[0] = row
+    g, _ := NewGrid(board)
+    return g
+}
+
+func TestNakedPairsRemoveTheirValuesFromTheRestOfTheUnit(t *testing.T) {
+    g := gridWithRow(Set{C(1,2), C(1,2,3), C(1,2), C(1,2,3,4)})
+
+    if !g.Apply(NakedPair.Apply(g)) {
+        t.Errorf("Expected to find the naked pair")
+    }
+    if !IsExactly(g.Board[0][1], C(3)) || !IsExactly(g.Board[0][3], C(3,4)) {
+        t.Errorf("Expected 1 and 2 to be removed from the rest of the row, but it is now %v", g.Board[0])
+    }
+}
+
+func TestHiddenPairsLoseTheirOtherCandidates(t *testing.T) {
+    g := gridWithRow(Set{C(1,2,3), C(3,4), C(1,2,4), C(3,4)})
+
+    if !g.Apply(HiddenPair.Apply(g)) {
+        t.Errorf("Expected to find the hidden pair")
+    }
+    if !IsExactly(g.Board[0][0], C(1,2)) || !IsExactly(g.Board[0][2], C(1,2)) {
+        t.Errorf("Expected the cells holding 1 and 2 to hold nothing else, but the row is now %v", g.Board[0])
+    }
+}
+
+func TestNakedQuadsRemoveTheirValuesFromTheRestOfTheUnit(t *testing.T) {
+    g := gridWithRow(Set{C(1,2), C(2,3), C(1,5,6,7,8,9), C(3,4), C(1,4), C(4,5,6,7,8,9), C(5,6,7,8,9), C(5,6,7,8,9), C(5,6,7,8,9)})
+
+    r := NakedQuad.Apply(g)
+    if len(r.Deductions) == 0 || r.Deductions[0].String() != "Naked quad: 1, 2, 3, 4 in r1c1, r1c2, r1c4, r1c5 in row 1, so r1c3 can't be 1; r1c6 can't be 4" {
+        t.Fatalf("Expected to find the naked quad, but got %v", r.Deductions)
+    }
+    g.Apply(r)
+    if !IsExactly(g.Board[0][2], C(5,6,7,8,9)) || !IsExactly(g.Board[0][5], C(5,6,7,8,9)) {
+        t.Errorf("Expected 1 to 4 to be removed from the rest of the row, but it is now %v", g.Board[0])
+    }
+}
+
+func TestHiddenQuadsLoseTheirOtherCandidates(t *testing.T) {
+    g := gridWithRow(Set{C(1,2,5), C(2,3,6), C(3,4,7), C(1,4,8,9), C(5,6,7,8,9), C(5,6,7,8,9), C(5,6,7,8,9), C(5,6,7,8,9), C(5,6,7,8,9)})
+
+    r := HiddenQuad.Apply(g)
+    if len(r.Deductions) != 1 || r.Deductions[0].Values != C(1,2,3,4) || len(r.Deductions[0].Cells) != 4 {
+        t.Fatalf("Expected to find the hidden quad, but got %v", r.Deductions)
+    }
+    g.Apply(r)
+    for c, expected := range []Cell{C(1,2), C(2,3), C(3,4), C(1,4)} {
+        if !IsExactly(g.Board[0][c], expected) {
+            t.Errorf("Expected r1c%d to hold %v, but it holds %v", c + 1, expected, g.Board[0][c])
+        }
+    }
+}
+
+func TestXWingRemovesTheValueFromItsColumns(t *testing.T) {
+    board := NewBoard(9)
+    for _, r := range []int{0, 4} {
+        for c := range board[r] {
+            board[r][c] = C(2,3,4,5,6,7,8,9)
+        }
+        board[r][2], board[r][5] = C(1,2), C(1,3)
+    }
+    g, _ := NewGrid(board)
+
+    if !g.Apply(XWing.Apply(g)) {
+        t.Errorf("Expected to find the X-Wing")
+    }
+    for r := range g.Board {
+        if r != 0 && r != 4 && (g.Board[r][2].Has(1) || g.Board[r][5].Has(1)) {
+            t.Errorf("Expected 1 to be removed from columns 3 and 6 of row %v", r + 1)
+        }
+    }
+}
+
+// A grid where 1 can only go in the given columns of the given rows.
+func fishGrid(places map[int][]int) *Grid {
+    board := NewBoard(9)
+    for r, cols := range places {
+        for c := range board[r] {
+            board[r][c] = C(2,3,4,5,6,7,8,9)
+        }
+        for _, c := range cols {
+            board[r][c] = C(1,2)
+        }
+    }
+    g, _ := NewGrid(board)
+    return g
+}
+
+func TestJellyfishRemovesTheValueFromItsColumns(t *testing.T) {
+    g := fishGrid(map[int][]int{0: {0, 3}, 2: {3, 6}, 4: {6, 8}, 6: {0, 8}})
+
+    r := Jellyfish.Apply(g)
+    if len(r.Deductions) == 0 || !strings.HasPrefix(r.Deductions[0].String(), "Jellyfish: 1 in rows 1, 3, 5, 7, covered by columns 1, 4, 7, 9, so r2c1 can't be 1") {
+        t.Fatalf("Expected to find the jellyfish, but got %v", r.Deductions)
+    }
+    g.Apply(r)
+    for row := range g.Board {
+        for _, c := range []int{0, 3, 6, 8} {
+            if row % 2 == 1 && g.Board[row][c].Has(1) {
+                t.Errorf("Expected 1 to be removed from r%dc%d", row + 1, c + 1)
+            }
+        }
+    }
+}
+
+func TestFinnedXWingOnlyRemovesTheValueWhereItSeesTheFins(t *testing.T) {
+    data := []struct {
+        places    map[int][]int
+        technique string
+        fins      []Pos
+    }{
+        {map[int][]int{0: {2, 5}, 4: {2, 3, 5}}, "Finned X-Wing", []Pos{{4, 3}}},
+        {map[int][]int{0: {2, 5}, 4: {2, 4}}, "Sashimi X-Wing", []Pos{{4, 4}}},
+    }
+    for _, datum := range data {
+        g := fishGrid(datum.places)
+        r := FinnedXWing.Apply(g)
+        expected := datum.technique + ": 1 in rows 1, 5, covered by columns 3, 6, with fins " + datum.fins[0].String() + ", so r4c6 can't be 1; r6c6 can't be 1"
+        found := false
+        for _, d := range r.Deductions {
+            if d.String() == expected {
+                found = len(d.Base) == 2 && len(d.Cover) == 2 && len(d.Fins) == 1 && d.Fins[0] == datum.fins[0]
+            }
+        }
+        if !found {
+            t.Errorf("Expected %q, but got %v", expected, r.Deductions)
+        }
+    }
+}
+
+// A grid where 1 can only go in the first row of the first square, and 2
+// only in the first square of row 5.
+func lockedGrid() *Grid {
+    board := NewBoard(9)
+    for r := 1; r < 3; r++ {
+        for c := 0; c < 3; c++ {
+            board[r][c] = C(2,3,4,5,6,7,8,9)
+        }
+    }
+    for c := 3; c < 9; c++ {
+        board[4][c] = C(1,3,4,5,6,7,8,9)
+    }
+    g, _ := NewGrid(board)
+    return g
+}
+
+func TestLockedCandidatesPointAndClaim(t *testing.T) {
+    g := lockedGrid()
+    r := LockedCandidates.Apply(g)
+
+    pointed, claimed := false, false
+    for _, d := range r.Deductions {
+        pointed = pointed || d.Unit.Kind == Square && d.Values == C(1)
+        claimed = claimed || d.Unit.Kind == Row && d.Values == C(2)
+        if d.Technique != "Locked candidates" {
+            t.Errorf("Unexpected technique %q", d.Technique)
+        }
+    }
+    if !pointed || !claimed {
+        t.Errorf("Expected to point and to claim, but got %v", r.Deductions)
+    }
+    g.Apply(r)
+    for c := 3; c < 9; c++ {
+        if g.Board[0][c].Has(1) {
+            t.Errorf("Expected 1 to be removed from r1c%d", c + 1)
+        }
+    }
+    for _, row := range []int{3, 5} {
+        for c := 0; c < 3; c++ {
+            if g.Board[row][c].Has(2) {
+                t.Errorf("Expected 2 to be removed from r%dc%d", row + 1, c + 1)
+            }
+        }
+    }
+}
+
+func TestSolvingUsesLockedCandidates(t *testing.T) {
+    g := lockedGrid()
+    if err := defaultSolver.Propagate(context.Background(), g); err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if g.Board[0][4].Has(1) || g.Board[3][0].Has(2) {
+        t.Errorf("Expected the solver to make box-line reductions, but got %v", g.Board)
+    }
+}
+
+func TestGradesAnEasyPuzzle(t *testing.T) {
+    grade, err := unsolved.clone().Grade()
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if grade.Tier != Easy || grade.Rating > 2.3 {
+        t.Errorf("Expected an easy puzzle, but got %+v", grade)
+    }
+    if len(grade.Used) == 0 || grade.Used[0].Technique != "Hidden single" || grade.Used[0].Count == 0 {
+        t.Errorf("Expected hidden singles to be used, but got %+v", grade.Used)
+    }
+}
+
+func TestGradesAHardPuzzleHarder(t *testing.T) {
+    extreme := Board{
+        Set{C( ),C( ),C(5),C(6),C( ),C( ),C( ),C( ),C(7)},
+        Set{C( ),C(6),C( ),C( ),C(4),C( ),C( ),C(8),C( )},
+        Set{C( ),C( ),C(9),C( ),C( ),C( ),C( ),C( ),C(1)},
+        Set{C(7),C( ),C( ),C( ),C( ),C( ),C(1),C( ),C( )},
+        Set{C( ),C(8),C( ),C( ),C(1),C( ),C( ),C(2),C( )},
+        Set{C( ),C( ),C(2),C( ),C( ),C( ),C( ),C( ),C(4)},
+        Set{C(5),C( ),C( ),C( ),C( ),C( ),C(3),C( ),C( )},
+        Set{C( ),C(2),C( ),C( ),C(9),C( ),C( ),C(6),C( )},
+        Set{C(4),C( ),C( ),C( ),C( ),C(7),C(5),C( ),C( )},
+    }
+
+    grade, err := extreme.Grade()
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if grade.Tier < Hard {
+        t.Errorf("Expected at least a hard puzzle, but got %+v", grade)
+    }
+    for i := 1; i < len(grade.Used); i++ {
+        if grade.Used[i - 1].Difficulty > grade.Used[i].Difficulty {
+            t.Errorf("Expected the techniques easiest first, but got %+v", grade.Used)
+        }
+    }
+}
+
+func TestGradeRefusesPuzzlesWithoutAUniqueSolution(t *testing.T) {
+    if _, err := NewBoard(4).Grade(); err != ErrMultipleSolutions {
+        t.Errorf("Expected ErrMultipleSolutions, but got %v", err)
+    }
+}
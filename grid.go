@@ -0,0 +1,236 @@
+package sudoku
+
+import (
+    "fmt"
+)
+
+// A cell's position on the board, counting from 0.
+type Pos struct {
+    Row, Col int
+}
+
+// Written the usual way, counting from 1: r3c5.
+func (p Pos) String() string {
+    return fmt.Sprintf("r%dc%d", p.Row + 1, p.Col + 1)
+}
+
+// A row, column or square: a group of cells which must hold every value once.
+type Unit struct {
+    Kind  UnitKind
+    Index int
+    Cells []Pos
+}
+
+func (u Unit) String() string {
+    return fmt.Sprintf("%v %d", u.Kind, u.Index + 1)
+}
+
+func (u Unit) contains(p Pos) bool {
+    for _, cell := range u.Cells {
+        if cell == p {
+            return true
+        }
+    }
+    return false
+}
+
+// The candidates of a board part way through being solved, and the units
+// which constrain them. Strategies look at a Grid to make their deductions.
+type Grid struct {
+    Board Board
+    Units []Unit
+    // Rules besides the units', easiest first.
+    Constraints []Constraint
+    // How many values there are: 1 to values.
+    values int
+    // The units each cell belongs to.
+    unitsAt [][][]int
+}
+
+// A grid for the board, with every blank cell's candidates filled out and
+// every given's value taken out of the cells which share a unit with it.
+// Returns a *ContradictionError if the givens break a unit.
+func NewGrid(board Board) (*Grid, error) {
+    return StandardGeometry(len(board)).NewGrid(board)
+}
+
+// NewGrid for a board of this geometry.
+func (geo *Geometry) NewGrid(board Board) (*Grid, error) {
+    size := len(board)
+    if size != geo.Size {
+        return nil, fmt.Errorf("sudoku: the board has %d rows, but the geometry needs %d", size, geo.Size)
+    }
+    g := &Grid{
+        Board: make(Board, size),
+        Units: geo.Units,
+        Constraints: geo.Constraints,
+        values: geo.values(),
+        unitsAt: make([][][]int, size),
+    }
+    for i := range board {
+        g.unitsAt[i] = make([][]int, size)
+    }
+    for i, u := range g.Units {
+        for _, p := range u.Cells {
+            g.unitsAt[p.Row][p.Col] = append(g.unitsAt[p.Row][p.Col], i)
+        }
+    }
+    // Cells in no unit, like the gaps around a Samurai's grids, are left
+    // empty.
+    for i := range board {
+        g.Board[i] = make(Set, len(board[i]))
+        for j, cell := range board[i] {
+            switch {
+                case !g.inPuzzle(i, j):
+                    g.Board[i][j] = C()
+                case cell.isEmpty():
+                    g.Board[i][j] = Create(g.values)
+                default:
+                    g.Board[i][j] = Copy(cell)
+            }
+        }
+    }
+    if err := g.conflict(); err != nil {
+        return nil, err
+    }
+    for i := range board {
+        for j, cell := range board[i] {
+            if cell.IsSolved() && g.inPuzzle(i, j) {
+                g.place(Pos{i, j}, cell.Value())
+            }
+        }
+    }
+    return g, nil
+}
+
+// Is the cell part of the puzzle, in at least one unit?
+func (g *Grid) inPuzzle(row, col int) bool {
+    return len(g.unitsAt[row][col]) > 0
+}
+
+// Is every cell of the puzzle solved?
+func (g *Grid) solved() bool {
+    for i := range g.Board {
+        for j, cell := range g.Board[i] {
+            if g.inPuzzle(i, j) && !cell.IsSolved() {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// A copy of the grid whose candidates can be changed independently.
+func (g *Grid) clone() *Grid {
+    out := *g
+    out.Board = g.Board.clone()
+    return &out
+}
+
+func (g *Grid) At(p Pos) Cell {
+    return g.Board[p.Row][p.Col]
+}
+
+// A copy of the candidates in a unit.
+func (g *Grid) Set(u Unit) Set {
+    return g.setInto(nil, u)
+}
+
+// Copy the candidates in a unit into set, reusing its storage when there
+// is room, so the strategies which look at every unit on every step don't
+// allocate for each of them.
+func (g *Grid) setInto(set Set, u Unit) Set {
+    set = set[:0]
+    for _, p := range u.Cells {
+        set = append(set, g.At(p))
+    }
+    return set
+}
+
+// Do the two cells share a unit?
+func (g *Grid) Sees(a, b Pos) bool {
+    for _, u := range g.unitsAt[a.Row][a.Col] {
+        for _, v := range g.unitsAt[b.Row][b.Col] {
+            if u == v {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// Every cell other than those given which sees all of them.
+func (g *Grid) seenByAll(cells ...Pos) []Pos {
+    seen := []Pos{}
+    for r := range g.Board {
+        Cells: for c := range g.Board[r] {
+            here := Pos{r, c}
+            for _, other := range cells {
+                if other == here || !g.Sees(other, here) {
+                    continue Cells
+                }
+            }
+            seen = append(seen, here)
+        }
+    }
+    return seen
+}
+
+// Put v in p, and take it out of every other cell which shares a unit.
+// If p can't hold v it is left empty. Reports whether anything changed.
+func (g *Grid) place(p Pos, v int) bool {
+    changed := g.At(p) != C(v)
+    g.Board[p.Row][p.Col] &= C(v)
+    for _, u := range g.unitsAt[p.Row][p.Col] {
+        for _, other := range g.Units[u].Cells {
+            if other != p && g.eliminate(other, C(v)) {
+                changed = true
+            }
+        }
+    }
+    return changed
+}
+
+// Remove candidates from a cell, reporting whether any were there to remove.
+func (g *Grid) eliminate(p Pos, values Cell) bool {
+    if g.At(p) & values == 0 {
+        return false
+    }
+    g.Board[p.Row][p.Col] = g.At(p).difference(values)
+    return true
+}
+
+// Make the placements and eliminations in the result, reporting whether
+// any of them changed the grid.
+func (g *Grid) Apply(r Result) bool {
+    changed := false
+    for _, p := range r.Placements {
+        if g.place(p.Pos, p.Value) {
+            changed = true
+        }
+    }
+    for _, e := range r.Eliminations {
+        if g.eliminate(e.Pos, e.Values) {
+            changed = true
+        }
+    }
+    return changed
+}
+
+// Check every unit and constraint, returning a *ContradictionError for the
+// first one which cannot be kept to.
+func (g *Grid) conflict() error {
+    var set Set
+    for _, u := range g.Units {
+        set = g.setInto(set, u)
+        if value, why := conflictIn(set); why != "" {
+            return &ContradictionError{u.Kind, u.Index, value, why}
+        }
+    }
+    for _, c := range g.Constraints {
+        if err := c.Check(g); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,83 @@
+package sudoku
+
+// The next step for someone solving a board by hand.
+type Hint struct {
+    Deduction
+    // The cell the hint is about: where the value goes, or the first cell
+    // which loses candidates.
+    Target Pos
+}
+
+// Blank cells of the board which are left with one candidate once the
+// board's values have been taken out of their peers.
+func nakedSinglesIn(board Board, g *Grid) []Deduction {
+    found := []Deduction{}
+    for r := range board {
+        for c, cell := range board[r] {
+            if !cell.IsSolved() && g.Board[r][c].IsSolved() {
+                p, v := Pos{r, c}, g.Board[r][c].Value()
+                found = append(found, Deduction{
+                    Technique: NakedSingle.Name(),
+                    Cells: []Pos{p},
+                    Values: C(v),
+                    Placements: []Placement{{p, v}},
+                })
+            }
+        }
+    }
+    return found
+}
+
+// Pick the deduction a person would spot first: one which places a value
+// over one which only eliminates, and one in a square over one in a row or
+// column.
+func easiest(deductions []Deduction) Deduction {
+    rank := func(d Deduction) int {
+        rank := 0
+        if len(d.Placements) == 0 {
+            rank += 2
+        }
+        if d.Unit == nil || d.Unit.Kind != Square {
+            rank++
+        }
+        return rank
+    }
+    best := deductions[0]
+    for _, d := range deductions[1:] {
+        if rank(d) < rank(best) {
+            best = d
+        }
+    }
+    return best
+}
+
+// The easiest deduction to be made next on the board, which may hold
+// pencil-marked candidates as well as values. Only the one deduction is
+// made: the rest of the board is left unsolved. Returns a
+// *ContradictionError if the board breaks a unit, and ErrNoProgress if
+// no technique can find anything, as when the board is already solved or
+// the next step would be a guess.
+func (input Board) Hint() (Hint, error) {
+    g, err := NewGrid(input)
+    if err != nil {
+        return Hint{}, err
+    }
+
+    for _, strategy := range Strategies {
+        deductions := strategy.Apply(g).Deductions
+        if strategy == NakedSingle {
+            deductions = append(nakedSinglesIn(input, g), deductions...)
+        }
+        if len(deductions) > 0 {
+            d := easiest(deductions)
+            hint := Hint{Deduction: d}
+            if len(d.Placements) > 0 {
+                hint.Target = d.Placements[0].Pos
+            } else {
+                hint.Target = d.Eliminations[0].Pos
+            }
+            return hint, nil
+        }
+    }
+    return Hint{}, ErrNoProgress
+}
@@ -0,0 +1,58 @@
+package sudoku
+
+import (
+    "testing"
+)
+
+func TestHintFindsTheEasiestNextStep(t *testing.T) {
+    board := unsolved.clone()
+
+    hint, err := board.Hint()
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if hint.Technique != "Hidden single" || hint.Unit == nil || hint.Unit.Kind != Square {
+        t.Errorf("Expected a hidden single in a square, but got %v", hint)
+    }
+    if v := hint.Placements[0].Value; solved[hint.Target.Row][hint.Target.Col].Value() != v {
+        t.Errorf("The hint put %v in %v, which is wrong", v, hint.Target)
+    }
+    if same, _ := board.Equals(unsolved); !same {
+        t.Errorf("Hint() should not change the board")
+    }
+}
+
+func TestHintFindsNakedSinglesInBlankCells(t *testing.T) {
+    board := NewBoard(9)
+    for i, v := range []int{2, 3, 4, 5} {
+        board[0][i + 1] = C(v)
+        board[i + 1][0] = C(v + 4)
+    }
+
+    hint, err := board.Hint()
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if hint.Technique != "Naked single" || hint.Target != (Pos{0, 0}) || hint.Placements[0].Value != 1 {
+        t.Errorf("Expected r1c1 to be a naked single 1, but got %v", hint)
+    }
+}
+
+func TestHintRespectsPencilMarks(t *testing.T) {
+    board := NewBoard(9)
+    board[0][0], board[0][4] = C(1,2), C(1,2)
+
+    hint, err := board.Hint()
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if hint.Technique != "Naked pair" || hint.Target != (Pos{0, 1}) {
+        t.Errorf("Expected the pencil-marked naked pair, but got %v", hint)
+    }
+}
+
+func TestHintHasNothingForASolvedBoard(t *testing.T) {
+    if _, err := solved.Hint(); err != ErrNoProgress {
+        t.Errorf("Expected ErrNoProgress, but got %v", err)
+    }
+}
@@ -0,0 +1,376 @@
+package sudoku
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// A killer cage: cells whose values add up to Sum, with none repeated.
+type Cage struct {
+    Sum   int
+    Cells []Pos
+}
+
+// Written as "15: r1c1, r1c2".
+func (c Cage) String() string {
+    return fmt.Sprintf("%d: %s", c.Sum, posList(c.Cells))
+}
+
+// Every set of size different values from 1 to max which add up to sum,
+// in order.
+func SumCombinations(sum, size, max int) []Cell {
+    combos := []Cell{}
+    var pick func(from, left, size int, chosen Cell)
+    pick = func(from, left, size int, chosen Cell) {
+        if size == 0 {
+            if left == 0 {
+                combos = append(combos, chosen)
+            }
+            return
+        }
+        for v := from; v <= max && v <= left; v++ {
+            pick(v + 1, left - v, size - 1, chosen.union(C(v)))
+        }
+    }
+    pick(1, sum, size, C())
+    return combos
+}
+
+// A copy of the geometry with killer cages. Returns an error if a cage
+// leaves the board, shares a cell with another, or has a sum its cells
+// can't make.
+func (geo *Geometry) WithCages(cages ...Cage) (*Geometry, error) {
+    k := &killer{}
+    caged := map[Pos]int{}
+    for i, cage := range cages {
+        if len(cage.Cells) == 0 {
+            return nil, fmt.Errorf("sudoku: cage %d has no cells", i + 1)
+        }
+        for _, p := range cage.Cells {
+            if p.Row < 0 || p.Row >= geo.Size || p.Col < 0 || p.Col >= geo.Size {
+                return nil, fmt.Errorf("sudoku: cage %d leaves the board at %v", i + 1, p)
+            }
+            if other, ok := caged[p]; ok {
+                return nil, fmt.Errorf("sudoku: cages %d and %d share %v", other + 1, i + 1, p)
+            }
+            caged[p] = i
+        }
+        if len(SumCombinations(cage.Sum, len(cage.Cells), geo.values())) == 0 {
+            return nil, fmt.Errorf("sudoku: cage %d can't add up to %d in %d cells", i + 1, cage.Sum, len(cage.Cells))
+        }
+        k.cages = append(k.cages, Unit{CageUnit, i, cage.Cells})
+        k.sums = append(k.sums, cage.Sum)
+    }
+    return geo.with(cageSums{k}, inniesAndOuties{k}), nil
+}
+
+// The cages shared by the killer constraints.
+type killer struct {
+    cages []Unit
+    sums  []int
+}
+
+// Each cage's cells keep only the candidates which can make its sum.
+type cageSums struct {
+    *killer
+}
+
+func (cageSums) Name() string {
+    return "Cage sum"
+}
+
+func (cageSums) Difficulty() float64 {
+    return 1.8
+}
+
+func (k cageSums) Apply(g *Grid) Result {
+    r := Result{}
+    for i := range k.cages {
+        cage := &k.cages[i]
+        r.add(g, sumDeduction(g, k.Name(), cage, cage.Cells, k.sums[i], true))
+    }
+    return r
+}
+
+// A cage breaks its rule once a value is solved twice in it, or its solved
+// cells add up to more than its sum, or all of them to anything else.
+func (k cageSums) Check(g *Grid) error {
+    for i, cage := range k.cages {
+        total, seen, solved := 0, C(), 0
+        for _, p := range cage.Cells {
+            if cell := g.At(p); cell.IsSolved() {
+                if seen.Has(cell.Value()) {
+                    return &ContradictionError{CageUnit, i, cell.Value(), fmt.Sprintf("%d appears more than once", cell.Value())}
+                }
+                seen = seen.union(cell)
+                total += cell.Value()
+                solved++
+            }
+        }
+        if total > k.sums[i] || solved == len(cage.Cells) && total != k.sums[i] {
+            return &ContradictionError{CageUnit, i, 0, fmt.Sprintf("the cells add up to %d, not %d", total, k.sums[i])}
+        }
+    }
+    return nil
+}
+
+// The most cells a sum from the innies and outies rule is worked out for.
+const maxInniesAndOuties = 4
+
+// Every unit adds up to the same total, so the cells of a unit which
+// aren't in a cage inside it make up the difference (the innies), and the
+// cells sticking out of the cages which cover a unit make up the excess
+// (the outies).
+type inniesAndOuties struct {
+    *killer
+}
+
+func (inniesAndOuties) Name() string {
+    return "Innies and outies"
+}
+
+func (inniesAndOuties) Difficulty() float64 {
+    return 2.5
+}
+
+func (k inniesAndOuties) Check(g *Grid) error {
+    return nil
+}
+
+func (k inniesAndOuties) Apply(g *Grid) Result {
+    r := Result{}
+    for i := range g.Units {
+        u := &g.Units[i]
+        total := len(u.Cells) * (len(u.Cells) + 1) / 2
+        inside, overlapping, outside := 0, 0, 0
+        innies := append([]Pos{}, u.Cells...)
+        outies := []Pos{}
+        for j, cage := range k.cages {
+            in := 0
+            for _, p := range cage.Cells {
+                if u.contains(p) {
+                    in++
+                }
+            }
+            if in == 0 {
+                continue
+            }
+            overlapping += k.sums[j]
+            outside += len(cage.Cells) - in
+            if in == len(cage.Cells) {
+                inside += k.sums[j]
+                innies = without(innies, cage.Cells)
+            }
+            for _, p := range cage.Cells {
+                if !u.contains(p) {
+                    outies = append(outies, p)
+                }
+            }
+        }
+        if len(innies) > 0 && len(innies) <= maxInniesAndOuties {
+            r.add(g, sumDeduction(g, k.Name(), u, innies, total - inside, true))
+        }
+        // The outies only add up if the cages cover the whole unit.
+        covered := len(without(u.Cells, k.cells())) == 0
+        if covered && outside > 0 && outside <= maxInniesAndOuties {
+            r.add(g, sumDeduction(g, k.Name(), u, outies, overlapping - total, false))
+        }
+    }
+    return r
+}
+
+// Every caged cell.
+func (k *killer) cells() []Pos {
+    cells := []Pos{}
+    for _, cage := range k.cages {
+        cells = append(cells, cage.Cells...)
+    }
+    return cells
+}
+
+// The cells in from, less those in cells.
+func without(from []Pos, cells []Pos) []Pos {
+    out := []Pos{}
+    Cells: for _, p := range from {
+        for _, q := range cells {
+            if p == q {
+                continue Cells
+            }
+        }
+        out = append(out, p)
+    }
+    return out
+}
+
+// Take out of the cells every candidate which can't be part of their
+// values adding up to sum. If distinct, no two of the values may be the
+// same; otherwise only cells which see each other must differ.
+func sumDeduction(g *Grid, technique string, u *Unit, cells []Pos, sum int, distinct bool) Deduction {
+    support := sumSupport(g, cells, sum, distinct)
+    d := Deduction{Technique: technique, Unit: u, Cells: cells}
+    for i, p := range cells {
+        d.Values = d.Values.union(support[i])
+        if out := g.At(p).difference(support[i]); out != 0 {
+            d.Eliminations = append(d.Eliminations, Elimination{p, out})
+        }
+    }
+    return d
+}
+
+// The candidates of each cell which appear in some way of filling the
+// cells with values adding up to sum, as for sumDeduction.
+func sumSupport(g *Grid, cells []Pos, sum int, distinct bool) []Cell {
+    candidates := make([]Cell, len(cells))
+    for i, p := range cells {
+        candidates[i] = g.At(p)
+    }
+    return sumSupportOf(g, cells, candidates, sum, distinct)
+}
+
+// sumSupport for cells which may only hold the given candidates.
+func sumSupportOf(g *Grid, cells []Pos, candidates []Cell, sum int, distinct bool) []Cell {
+    support := make([]Cell, len(cells))
+    values := make([]int, len(cells))
+    // Whether the cells from i on can be filled, given the values used so
+    // far, keyed by i, the values used and what is left of the sum. Only
+    // distinct cells can be remembered this way.
+    type state struct {
+        i    int
+        used Cell
+        left int
+    }
+    known := map[state]bool{}
+
+    var fill func(i int, used Cell, left int) bool
+    fill = func(i int, used Cell, left int) bool {
+        if i == len(cells) {
+            return left == 0
+        }
+        key := state{i, used, left}
+        if ok, seen := known[key]; seen && distinct {
+            return ok
+        }
+        ok := false
+        Values: for _, v := range candidates[i].Values() {
+            if v > left {
+                break
+            }
+            if distinct && used.Has(v) {
+                continue
+            }
+            for j := 0; j < i && !distinct; j++ {
+                if values[j] == v && g.Sees(cells[i], cells[j]) {
+                    continue Values
+                }
+            }
+            values[i] = v
+            if fill(i + 1, used.union(C(v)), left - v) {
+                support[i] = support[i].union(C(v))
+                ok = true
+            }
+        }
+        if distinct {
+            known[key] = ok
+        }
+        return ok
+    }
+    fill(0, C(), sum)
+    return support
+}
+
+// Parse killer cages written as a grid, laid out as for ParseLayout, with
+// the same letter (or other character) in every cell of a cage and '.' in
+// cells outside any, followed by each cage's sum: "a=10 b=7", on as many
+// lines as needed. Cages are numbered in the order they are first seen.
+func ParseCages(input string) ([]Cage, error) {
+    lines := strings.Split(input, "\n")
+    grid := 0
+    for grid < len(lines) && !strings.Contains(lines[grid], "=") {
+        grid++
+    }
+
+    cages := []Cage{}
+    firsts := []symbol{}
+    index := map[rune]int{}
+    row := 0
+    for i, line := range lines[:grid] {
+        line = strings.TrimRight(line, "\r")
+        if isSeparator(line) {
+            continue
+        }
+        col := 0
+        for j, r := range []rune(line) {
+            if isDecoration(r) {
+                continue
+            }
+            if r != '.' {
+                k, ok := index[r]
+                if !ok {
+                    k = len(cages)
+                    index[r] = k
+                    cages = append(cages, Cage{})
+                    firsts = append(firsts, symbol{r, i + 1, j + 1})
+                }
+                cages[k].Cells = append(cages[k].Cells, Pos{row, col})
+            }
+            col++
+        }
+        if col > 0 {
+            row++
+        }
+    }
+    if len(cages) == 0 {
+        return nil, &ParseError{1, 0, "no cages found"}
+    }
+
+    summed := map[rune]bool{}
+    for i := grid; i < len(lines); i++ {
+        for _, field := range fieldsOf(lines[i], i + 1) {
+            parts := strings.SplitN(field.text, "=", 2)
+            label := []rune(parts[0])
+            if len(parts) != 2 || len(label) != 1 {
+                return nil, &ParseError{field.line, field.col, fmt.Sprintf("expected a cage and its sum, like a=10, but found %q", field.text)}
+            }
+            k, ok := index[label[0]]
+            if !ok {
+                return nil, &ParseError{field.line, field.col, fmt.Sprintf("there is no cage %q", label[0])}
+            }
+            sum, err := strconv.Atoi(parts[1])
+            if err != nil || sum <= 0 {
+                return nil, &ParseError{field.line, field.col, fmt.Sprintf("%q is not a sum", parts[1])}
+            }
+            cages[k].Sum = sum
+            summed[label[0]] = true
+        }
+    }
+    for _, first := range firsts {
+        if !summed[first.r] {
+            return nil, &ParseError{first.line, first.col, fmt.Sprintf("cage %q has no sum", first.r)}
+        }
+    }
+    return cages, nil
+}
+
+// A word of the input and where it starts.
+type field struct {
+    text      string
+    line, col int
+}
+
+// The words of a line, separated by spaces, tabs or commas.
+func fieldsOf(line string, number int) []field {
+    fields := []field{}
+    runes := []rune(strings.TrimRight(line, "\r"))
+    for start := 0; start < len(runes); {
+        end := start
+        for end < len(runes) && !strings.ContainsRune(" \t,", runes[end]) {
+            end++
+        }
+        if end > start {
+            fields = append(fields, field{string(runes[start:end]), number, start + 1})
+        }
+        start = end + 1
+    }
+    return fields
+}
@@ -0,0 +1,149 @@
+package sudoku
+
+import (
+    matchers "github.com/tychofreeman/go-matchers"
+    "testing"
+    "context"
+    "errors"
+    "io/ioutil"
+)
+
+// The cages in testdata/killer.txt are filled only by solved.
+func killerGeometry(t *testing.T) *Geometry {
+    text, err := ioutil.ReadFile("testdata/killer.txt")
+    if err != nil {
+        t.Fatalf("Could not read the cages: %v", err)
+    }
+    cages, err := ParseCages(string(text))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    geo, err := StandardGeometry(9).WithCages(cages...)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    return geo
+}
+
+func TestSumCombinations(t *testing.T) {
+    data := []struct {
+        sum, size int
+        expected []Cell
+    }{
+        {10, 4, []Cell{C(1,2,3,4)}},
+        {17, 2, []Cell{C(8,9)}},
+        {5, 2, []Cell{C(1,4), C(2,3)}},
+        {45, 9, []Cell{C(1,2,3,4,5,6,7,8,9)}},
+        {4, 2, []Cell{C(1,3)}},
+        {2, 2, []Cell{}},
+    }
+    for _, datum := range data {
+        combos := SumCombinations(datum.sum, datum.size, 9)
+        matchers.AssertThat(t, combos, matchers.Equals(datum.expected))
+    }
+}
+
+func TestParsesCages(t *testing.T) {
+    text, _ := ioutil.ReadFile("testdata/killer.txt")
+    cages, err := ParseCages(string(text))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if len(cages) != 40 || cages[0].String() != "10: r1c1, r1c2" || cages[3].String() != "20: r1c7, r1c8, r2c7" {
+        t.Errorf("Parsed the wrong cages %v", cages)
+    }
+}
+
+func TestParseCagesSaysWhereTheInputIsWrong(t *testing.T) {
+    for input, expected := range map[string]string{
+        "ab\nab\na=3 b=7 c=2": "sudoku: line 3, column 9: there is no cage 'c'",
+        "ab\nab\na=3, b=x": "sudoku: line 3, column 6: \"x\" is not a sum",
+        "ab\nab\na=3 b": "sudoku: line 3, column 5: expected a cage and its sum, like a=10, but found \"b\"",
+        "ab\nab\na=3": "sudoku: line 1, column 2: cage 'b' has no sum",
+        "..\n..": "sudoku: line 1: no cages found",
+    } {
+        _, err := ParseCages(input)
+        if err == nil || err.Error() != expected {
+            t.Errorf("For %q expected %q, but got %v", input, expected, err)
+        }
+    }
+}
+
+func TestWithCagesRejectsBadCages(t *testing.T) {
+    for _, cages := range [][]Cage{
+        {{3, []Pos{{0, 0}, {0, 1}}}, {4, []Pos{{0, 1}, {0, 2}}}},
+        {{2, []Pos{{0, 0}, {0, 1}}}},
+        {{5, []Pos{{0, 9}}}},
+        {{5, nil}},
+    } {
+        if _, err := StandardGeometry(9).WithCages(cages...); err == nil {
+            t.Errorf("Expected an error for %v", cages)
+        }
+    }    // A Samurai's board is 21 cells on a side, but its values only go to 9.
+    if _, err := SamuraiGeometry().WithCages(Cage{30, []Pos{{0, 0}, {0, 1}}}); err == nil {
+        t.Errorf("Expected an error for two cells adding up to 30 on a Samurai")
+    }
+}
+
+func TestSolvesKillerSudoku(t *testing.T) {
+    solution, err := killerGeometry(t).Solve(context.Background(), NewBoard(9))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := solution.Equals(solved); !same {
+        t.Errorf("Solved the cages wrongly: %v", msg)
+    }
+}
+
+func TestGradesKillerSudokuWithTheCageTechniques(t *testing.T) {
+    grade, err := killerGeometry(t).Grade(NewBoard(9))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    used := map[string]bool{}
+    for _, use := range grade.Used {
+        used[use.Technique] = true
+    }
+    if !used["Cage sum"] || !used["Innies and outies"] || grade.Rating != 2.5 {
+        t.Errorf("Expected the cage techniques to be used, but got %+v", grade)
+    }
+}
+
+func TestCageSumsKeepOnlyTheCombinations(t *testing.T) {
+    geo, _ := StandardGeometry(9).WithCages(Cage{3, []Pos{{0, 0}, {0, 1}}}, Cage{17, []Pos{{1, 0}, {2, 0}}})
+    g, _ := geo.NewGrid(NewBoard(9))
+    NewSolver().Step(g)
+    if g.At(Pos{0, 0}) != C(1, 2) || g.At(Pos{0, 1}) != C(1, 2) || g.At(Pos{1, 0}) != C(8, 9) {
+        t.Errorf("Expected the cages to be narrowed, but got\n%v", g.Board.DebugString())
+    }
+}
+
+func TestInniesMakeUpTheRestOfAUnit(t *testing.T) {
+    cages := []Cage{}
+    for c := 0; c < 8; c += 2 {
+        cages = append(cages, Cage{solved[0][c].Value() + solved[0][c + 1].Value(), []Pos{{0, c}, {0, c + 1}}})
+    }
+    geo, _ := StandardGeometry(9).WithCages(cages...)
+    g, _ := geo.NewGrid(NewBoard(9))
+
+    r := Result{}
+    for _, c := range geo.Constraints {
+        if c.Name() == "Innies and outies" {
+            r = c.Apply(g)
+        }
+    }
+    if len(r.Deductions) == 0 || r.Deductions[0].String() != "Innies and outies: 4 in r1c9 in row 1, so r1c9 can't be 1 or 2 or 3 or 5 or 6 or 7 or 8 or 9" {
+        t.Errorf("Expected r1c9 to be 4, but got %v", r.Deductions)
+    }
+}
+
+func TestReportsContradictionsInCages(t *testing.T) {
+    geo, _ := StandardGeometry(9).WithCages(Cage{5, []Pos{{0, 0}, {0, 1}}})
+    board := NewBoard(9)
+    board[0][0], board[0][1] = C(1), C(2)
+    _, err := geo.Solve(context.Background(), board)
+    var contradiction *ContradictionError
+    if !errors.As(err, &contradiction) || contradiction.Unit != CageUnit || err.Error() != "sudoku: contradiction in cage 1: the cells add up to 3, not 5" {
+        t.Errorf("Expected a contradiction in the cage, but got %v", err)
+    }
+}
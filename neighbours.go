@@ -0,0 +1,127 @@
+package sudoku
+
+import (
+    "fmt"
+    "strings"
+)
+
+// The cells near p which, under some rule, mustn't hold the same value
+// as it. Cells off the board are ignored, so a neighbourhood needn't know
+// the board's size.
+type Neighbourhood func(p Pos) []Pos
+
+// Cells a knight's move from p, as in anti-knight sudoku.
+func KnightMoves(p Pos) []Pos {
+    return offsets(p, [][2]int{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}})
+}
+
+// Cells a king's move from p, as in anti-king sudoku.
+func KingMoves(p Pos) []Pos {
+    return offsets(p, [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}})
+}
+
+func offsets(p Pos, moves [][2]int) []Pos {
+    cells := make([]Pos, len(moves))
+    for i, m := range moves {
+        cells[i] = Pos{p.Row + m[0], p.Col + m[1]}
+    }
+    return cells
+}
+
+// Cells next to p in its row or column.
+func Orthogonal(p Pos) []Pos {
+    return offsets(p, [][2]int{{-1, 0}, {0, -1}, {0, 1}, {1, 0}})
+}
+
+// Every pair of cells on the board the neighbourhood links, once each and
+// in reading order; a cell counts as a neighbour of its neighbours.
+func (geo *Geometry) pairsOf(neighbours Neighbourhood) [][2]Pos {
+    pairs := [][2]Pos{}
+    linked := map[[2]Pos]bool{}
+    for r := 0; r < geo.Size; r++ {
+        for c := 0; c < geo.Size; c++ {
+            p := Pos{r, c}
+            for _, q := range neighbours(p) {
+                if q.Row < 0 || q.Row >= geo.Size || q.Col < 0 || q.Col >= geo.Size || q == p {
+                    continue
+                }
+                pair := [2]Pos{p, q}
+                if q.Row < p.Row || q.Row == p.Row && q.Col < p.Col {
+                    pair = [2]Pos{q, p}
+                }
+                if !linked[pair] {
+                    linked[pair] = true
+                    pairs = append(pairs, pair)
+                }
+            }
+        }
+    }
+    return pairs
+}
+
+// A copy of the geometry in which no two cells the neighbourhood links
+// may hold the same value. The rule is named in deductions and errors.
+func (geo *Geometry) WithDifferent(name string, neighbours Neighbourhood) *Geometry {
+    d := &different{name: name, pairs: geo.pairsOf(neighbours), near: map[Pos][]Pos{}}
+    for _, pair := range d.pairs {
+        d.near[pair[0]] = append(d.near[pair[0]], pair[1])
+        d.near[pair[1]] = append(d.near[pair[1]], pair[0])
+    }
+    return geo.with(d)
+}
+
+// A copy of the geometry in which cells a knight's move apart differ.
+func (geo *Geometry) WithAntiKnight() *Geometry {
+    return geo.WithDifferent("Anti-knight", KnightMoves)
+}
+
+// A copy of the geometry in which cells a king's move apart differ.
+func (geo *Geometry) WithAntiKing() *Geometry {
+    return geo.WithDifferent("Anti-king", KingMoves)
+}
+
+// A solved cell's value can't go in any of its neighbours.
+type different struct {
+    name  string
+    pairs [][2]Pos
+    near  map[Pos][]Pos
+}
+
+func (d *different) Name() string {
+    return d.name
+}
+
+// As easy as seeing a value in a cell's row.
+func (d *different) Difficulty() float64 {
+    return 1.2
+}
+
+func (d *different) Apply(g *Grid) Result {
+    r := Result{}
+    for row := range g.Board {
+        for col, cell := range g.Board[row] {
+            p := Pos{row, col}
+            if !cell.IsSolved() || len(d.near[p]) == 0 {
+                continue
+            }
+            r.add(g, Deduction{
+                Technique: d.name,
+                Cells: []Pos{p},
+                Values: cell,
+                Eliminations: eliminations(cell, d.near[p]...),
+            })
+        }
+    }
+    return r
+}
+
+// Neighbours break the rule once both are solved with the same value.
+func (d *different) Check(g *Grid) error {
+    for i, pair := range d.pairs {
+        a, b := g.At(pair[0]), g.At(pair[1])
+        if a.IsSolved() && a == b {
+            return &ContradictionError{PairUnit, i, a.Value(), fmt.Sprintf("%v and %v are both %d, against the %s rule", pair[0], pair[1], a.Value(), strings.ToLower(d.name))}
+        }
+    }
+    return nil
+}
@@ -0,0 +1,83 @@
+package sudoku
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+)
+
+// Every pair of cells on the board the neighbourhood links, once each.
+func pairsOf(geo *Geometry) [][2]Pos {
+    for _, c := range geo.Constraints {
+        if d, ok := c.(*different); ok {
+            return d.pairs
+        }
+    }
+    return nil
+}
+
+func TestNeighbourhoodsStayOnTheBoard(t *testing.T) {
+    // 4(n-1)(n-2) knight's moves and 2(n-1)(2n-1) king's moves fit on an
+    // n-by-n board.
+    if n := len(pairsOf(StandardGeometry(9).WithAntiKnight())); n != 224 {
+        t.Errorf("Expected 224 knight's moves, but found %d", n)
+    }
+    if n := len(pairsOf(StandardGeometry(9).WithAntiKing())); n != 272 {
+        t.Errorf("Expected 272 king's moves, but found %d", n)
+    }
+}
+
+func TestAntiKnightTakesASolvedValueOutOfItsNeighbours(t *testing.T) {
+    board := NewBoard(9)
+    board[4][4] = C(5)
+    geo := StandardGeometry(9).WithAntiKnight()
+    g, err := geo.NewGrid(board)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    r := geo.Constraints[0].Apply(g)
+    if len(r.Deductions) != 1 || len(r.Eliminations) != 8 {
+        t.Fatalf("Expected one deduction with 8 eliminations, but got %v", r.Deductions)
+    }
+    expected := "Anti-knight: 5 in r5c5, so r3c4 can't be 5; r3c6 can't be 5;"
+    if !strings.HasPrefix(r.Deductions[0].String(), expected) {
+        t.Errorf("Expected %q, but got %q", expected, r.Deductions[0])
+    }
+}
+
+func TestAntiKingReportsContradictions(t *testing.T) {
+    // The two 5s touch at the corners of their squares, but share no unit.
+    board := NewBoard(9)
+    board[2][2] = C(5)
+    board[3][3] = C(5)
+    if _, err := NewGrid(board); err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    _, err := StandardGeometry(9).WithAntiKing().NewGrid(board)
+    var contradiction *ContradictionError
+    if !errors.As(err, &contradiction) || contradiction.Unit != PairUnit || !strings.Contains(err.Error(), "r3c3 and r4c4 are both 5, against the anti-king rule") {
+        t.Errorf("Expected a contradiction in a pair, but got %v", err)
+    }
+}
+
+func TestGeneratesAndSolvesAntiKnightPuzzles(t *testing.T) {
+    geo := StandardGeometry(9).WithAntiKnight()
+    puzzle, err := Generate(GenerateOptions{Seed: 4, Geometry: geo})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    solution, err := geo.Solve(context.Background(), puzzle)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    for _, pair := range pairsOf(geo) {
+        if solution[pair[0].Row][pair[0].Col] == solution[pair[1].Row][pair[1].Col] {
+            t.Errorf("%v and %v are the same in\n%#v", pair[0], pair[1], solution)
+        }
+    }
+    // The rule is what makes the solution unique.
+    if puzzle.CountSolutions(2) != 2 {
+        t.Errorf("Expected the puzzle to need the rule")
+    }
+}
@@ -0,0 +1,189 @@
+package sudoku
+
+import (
+    "fmt"
+    "math"
+    "strings"
+)
+
+// The symbols a board is written with: the first stands for 1, the next
+// for 2 and so on. A board can be as big as its alphabet is long.
+type Alphabet string
+
+const (
+    // 1-9 then A-P, enough for 25x25 boards. Parse and Board.String use it.
+    Digits Alphabet = "123456789ABCDEFGHIJKLMNOP"
+    // 0-9 then A-F, as 16x16 boards are often written. '0' is a value, so
+    // only '.' is a blank.
+    Hex Alphabet = "0123456789ABCDEF"
+    // A-Y, as 25x25 boards are often written.
+    Letters Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXY"
+)
+
+// An alphabet of the given symbols, in order. Returns an error if one is
+// repeated, or is '.' or a character which lays out a grid, or if there
+// are more than the 64 values a Cell can hold.
+func NewAlphabet(symbols string) (Alphabet, error) {
+    runes := []rune(symbols)
+    if len(runes) == 0 || len(runes) > 64 {
+        return "", fmt.Errorf("sudoku: an alphabet needs 1 to 64 symbols, not %d", len(runes))
+    }
+    seen := map[rune]bool{}
+    for _, r := range runes {
+        switch {
+            case r == '.' || r == '-' || isDecoration(r):
+                return "", fmt.Errorf("sudoku: %q can't be a symbol", r)
+            case seen[r]:
+                return "", fmt.Errorf("sudoku: %q is in the alphabet twice", r)
+        }
+        seen[r] = true
+    }
+    return Alphabet(symbols), nil
+}
+
+// Where and why some input couldn't be parsed. Lines and columns count from 1.
+type ParseError struct {
+    Line, Col int
+    Msg       string
+}
+
+func (e *ParseError) Error() string {
+    if e.Col == 0 {
+        return fmt.Sprintf("sudoku: line %d: %s", e.Line, e.Msg)
+    }
+    return fmt.Sprintf("sudoku: line %d, column %d: %s", e.Line, e.Col, e.Msg)
+}
+
+// Characters which only decorate a grid.
+func isDecoration(r rune) bool {
+    return r == ' ' || r == '\t' || r == '|' || r == '+'
+}
+
+// Lines like ------+------ which separate the squares of a grid.
+func isSeparator(line string) bool {
+    return strings.Trim(line, "-+| \t") == "" && strings.Contains(line, "-")
+}
+
+// A symbol read from the input, and where it was.
+type symbol struct {
+    r         rune
+    line, col int
+}
+
+// Parse a board written as one line of cells, as in "..5.6....7...", or
+// as a grid with a line per row, like solved.txt. Values are 1-9 then A-P
+// for boards bigger than 9x9; '.' or '0' is a blank. Spaces, '|' and '+'
+// can lay out a grid, as can lines of dashes between its squares.
+func Parse(input string) (Board, error) {
+    return Digits.Parse(input)
+}
+
+// Parse a board written with the alphabet, laid out as for Parse. '.' is a
+// blank, as is '0' unless it is one of the symbols. Lower case letters are
+// read as upper case, unless the alphabet has some of its own.
+func (a Alphabet) Parse(input string) (Board, error) {
+    rows := [][]symbol{}
+    for i, line := range strings.Split(input, "\n") {
+        line = strings.TrimRight(line, "\r")
+        if isSeparator(line) {
+            continue
+        }
+        row := []symbol{}
+        for j, r := range []rune(line) {
+            if !isDecoration(r) {
+                row = append(row, symbol{r, i + 1, j + 1})
+            }
+        }
+        if len(row) > 0 {
+            rows = append(rows, row)
+        }
+    }
+
+    if len(rows) == 0 {
+        return nil, &ParseError{1, 0, "no board found"}
+    }
+    var cells []symbol
+    size := len(rows)
+    if len(rows) == 1 {
+        cells = rows[0]
+        size = int(math.Sqrt(float64(len(cells))))
+        if size * size != len(cells) {
+            return nil, &ParseError{cells[0].line, 0, fmt.Sprintf("%d cells can't make a square board", len(cells))}
+        }
+    } else {
+        for _, row := range rows {
+            if len(row) != size {
+                return nil, &ParseError{row[0].line, 0, fmt.Sprintf("expected %d cells in the row, but found %d", size, len(row))}
+            }
+            cells = append(cells, row...)
+        }
+    }
+
+    board := NewBoard(size)
+    for i, s := range cells {
+        v, err := a.valueOf(s, size)
+        if err != nil {
+            return nil, err
+        }
+        board[i / size][i % size] = C(v)
+    }
+    return board, nil
+}
+
+// The value of a symbol on a board of the given size; 0 for a blank.
+func (a Alphabet) valueOf(s symbol, size int) (int, error) {
+    symbols := []rune(string(a))
+    index := func(r rune) int {
+        for i, symbol := range symbols {
+            if symbol == r {
+                return i + 1
+            }
+        }
+        return 0
+    }
+    v := index(s.r)
+    if v == 0 && s.r >= 'a' && s.r <= 'z' && strings.ToUpper(string(a)) == string(a) {
+        v = index(s.r - 'a' + 'A')
+    }
+    if v == 0 && (s.r == '.' || s.r == '0') {
+        return 0, nil
+    }
+    if v == 0 {
+        return 0, &ParseError{s.line, s.col, fmt.Sprintf("unexpected %q", s.r)}
+    }
+    if v > size {
+        return 0, &ParseError{s.line, s.col, fmt.Sprintf("%q is out of range for a %d-by-%d board", s.r, size, size)}
+    }
+    return v, nil
+}
+
+// The symbol the alphabet has for v, or '?' if there isn't one.
+func (a Alphabet) symbolFor(v int) rune {
+    symbols := []rune(string(a))
+    if v < 1 || v > len(symbols) {
+        return '?'
+    }
+    return symbols[v - 1]
+}
+
+// The board as one line of cells, as Parse reads them, with '.' for every
+// cell that isn't solved.
+func (input Board) String() string {
+    return Digits.Format(input)
+}
+
+// The board as one line of cells written with the alphabet, as its Parse
+// reads them.
+func (a Alphabet) Format(board Board) string {
+    out := make([]rune, 0, len(board) * len(board))
+    for _, row := range board {
+        for _, cell := range row {
+            if cell.IsSolved() {
+                out = append(out, a.symbolFor(cell.Value()))
+            } else {
+                out = append(out, '.')
+            }
+        }
+    }
+    return string(out)
+}
@@ -0,0 +1,160 @@
+package sudoku
+
+import (
+    "io/ioutil"
+    "strings"
+    "testing"
+)
+
+const unsolvedLine = ".1.6.7..4" + ".42......" + "87.3..6.." + ".8..7..2." + "...893..." + ".3..6..1." + "..8..6.45" + "......17." + "4..9.8.6."
+
+func TestParsesALineOfCells(t *testing.T) {
+    board, err := Parse(unsolvedLine)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := board.Equals(unsolved); !same {
+        t.Errorf("Parsed the wrong board: %v", msg)
+    }
+    if board.String() != unsolvedLine {
+        t.Errorf("Expected String() to give back %v, but got %v", unsolvedLine, board.String())
+    }
+}
+
+func TestParsesZerosAsBlanks(t *testing.T) {
+    board, err := Parse(strings.Replace(unsolvedLine, ".", "0", -1))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := board.Equals(unsolved); !same {
+        t.Errorf("Parsed the wrong board: %v", msg)
+    }
+}
+
+func TestParsesTheGridLayoutOfSolvedTxt(t *testing.T) {
+    text, err := ioutil.ReadFile("solved.txt")
+    if err != nil {
+        t.Fatalf("Could not read solved.txt: %v", err)
+    }
+
+    board, err := Parse(string(text))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if !board.IsSolved() || len(board) != 9 || board[0][0].Value() != 9 || board[8][8].Value() != 3 {
+        t.Errorf("Parsed the wrong board:\n%#v", board)
+    }
+}
+
+func TestParsesItsOwnGoString(t *testing.T) {
+    board, err := Parse(solved.GoString())
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := board.Equals(solved); !same {
+        t.Errorf("Parsed the wrong board: %v", msg)
+    }
+}
+
+func TestParsesOtherSizes(t *testing.T) {
+    for _, input := range []string{
+        "12..34..21..43..",
+        strings.Repeat(".", 15) + "G" + strings.Repeat(".", 240),
+        "p" + strings.Repeat(".", 624),
+    } {
+        board, err := Parse(input)
+        if err != nil {
+            t.Errorf("Unexpected error %v", err)
+            continue
+        }
+        if board.String() != strings.ToUpper(input) {
+            t.Errorf("Expected %v, but got %v", input, board.String())
+        }
+    }
+}
+
+func TestParseSaysWhereTheInputIsWrong(t *testing.T) {
+    for input, expected := range map[string]string{
+        "1234\n4x21\n....\n....": "sudoku: line 2, column 2: unexpected 'x'",
+        "12\n345": "sudoku: line 2: expected 2 cells in the row, but found 3",
+        "1234": "sudoku: line 1, column 3: '3' is out of range for a 2-by-2 board",
+        "12345": "sudoku: line 1: 5 cells can't make a square board",
+        "": "sudoku: line 1: no board found",
+    } {
+        _, err := Parse(input)
+        if err == nil || err.Error() != expected {
+            t.Errorf("For %q expected %q, but got %v", input, expected, err)
+        }
+    }
+}
+
+func TestParsesHexAndLetterAlphabets(t *testing.T) {
+    hex := "0" + strings.Repeat(".", 14) + "F" + strings.Repeat(".", 240)
+    board, err := Hex.Parse(hex)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if board[0][0] != C(1) || board[0][15] != C(16) {
+        t.Errorf("Expected 1 and 16, but got %v and %v", board[0][0], board[0][15])
+    }
+    if Hex.Format(board) != hex || board.String() != "1" + strings.Repeat(".", 14) + "G" + strings.Repeat(".", 240) {
+        t.Errorf("Expected %v, but got %v", hex, Hex.Format(board))
+    }
+
+    letters := "y" + strings.Repeat(".", 623) + "A"
+    board, err = Letters.Parse(letters)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if board[0][0] != C(25) || board[24][24] != C(1) {
+        t.Errorf("Expected 25 and 1, but got %v and %v", board[0][0], board[24][24])
+    }
+    if _, err := Letters.Parse("Z" + strings.Repeat(".", 624)); err == nil {
+        t.Errorf("Expected an error for Z")
+    }
+}
+
+func TestParsesItsOwnDrawingInAnyAlphabet(t *testing.T) {
+    puzzle, err := Generate(GenerateOptions{Size: 16, Seed: 1, Clues: 120})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    board := puzzle.Solve()
+    for _, a := range []Alphabet{Digits, Hex, Letters} {
+        drawn := a.Draw(board, nil)
+        if strings.Contains(drawn, "10") {
+            t.Errorf("Expected one symbol per cell, but got\n%s", drawn)
+        }
+        back, err := a.Parse(drawn)
+        if err != nil {
+            t.Errorf("Unexpected error %v", err)
+            continue
+        }
+        if same, msg := back.Equals(board); !same {
+            t.Errorf("Parsed the wrong board: %v", msg)
+        }
+    }
+}
+
+func TestCustomAlphabets(t *testing.T) {
+    a, err := NewAlphabet("abcd")
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    board, err := a.Parse("ab..cd..ba..dc..")
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if board.String() != "12..34..21..43.." {
+        t.Errorf("Expected 12..34..21..43.., but got %v", board)
+    }
+    // Lower case letters are symbols of their own here.
+    if _, err := a.Parse("AB..CD..BA..DC.."); err == nil {
+        t.Errorf("Expected an error for upper case letters")
+    }
+    for _, symbols := range []string{"", "1231", "12.4", "12 4", strings.Repeat("x", 65)} {
+        if _, err := NewAlphabet(symbols); err == nil {
+            t.Errorf("Expected an error for %q", symbols)
+        }
+    }
+}
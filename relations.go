@@ -0,0 +1,229 @@
+package sudoku
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// A rule the values of two cells must keep to, like the white dot of
+// Kropki sudoku. Holds reports whether a in the first cell and b in the
+// second keep to it.
+type Relation struct {
+    Name  string
+    Holds func(a, b int) bool
+}
+
+var (
+    // The values aren't consecutive: no 4 next to a 5.
+    NonConsecutive = Relation{"Non-consecutive", func(a, b int) bool { return a - b != 1 && b - a != 1 }}
+    // A Kropki white dot: the values are consecutive.
+    WhiteDot = Relation{"White dot", func(a, b int) bool { return a - b == 1 || b - a == 1 }}
+    // A Kropki black dot: one value is twice the other.
+    BlackDot = Relation{"Black dot", func(a, b int) bool { return a == 2 * b || b == 2 * a }}
+    // The values add up to 10.
+    XSum = Relation{"X", func(a, b int) bool { return a + b == 10 }}
+    // The values add up to 5.
+    VSum = Relation{"V", func(a, b int) bool { return a + b == 5 }}
+    // The first value is the smaller, as in greater-than sudoku.
+    LessThan = Relation{"Less than", func(a, b int) bool { return a < b }}
+    // The first value is the larger.
+    GreaterThan = Relation{"Greater than", func(a, b int) bool { return a > b }}
+)
+
+// The relations ParseMarkings knows, by the names it reads them by.
+var Relations = map[string]Relation{
+    "nonconsecutive": NonConsecutive,
+    "white": WhiteDot,
+    "black": BlackDot,
+    "x": XSum,
+    "v": VSum,
+    "<": LessThan,
+    ">": GreaterThan,
+}
+
+// A relation between two cells of a puzzle.
+type Marking struct {
+    Relation Relation
+    Cells    [2]Pos
+}
+
+// Written as "White dot: r1c1, r1c2".
+func (m Marking) String() string {
+    return fmt.Sprintf("%s: %s", m.Relation.Name, posList(m.Cells[:]))
+}
+
+// A copy of the geometry whose cells keep to the markings. Returns an
+// error if a marking leaves the board or relates a cell to itself.
+func (geo *Geometry) WithMarkings(markings ...Marking) (*Geometry, error) {
+    byName := map[string]*related{}
+    names := []string{}
+    for i, m := range markings {
+        for _, p := range m.Cells {
+            if p.Row < 0 || p.Row >= geo.Size || p.Col < 0 || p.Col >= geo.Size {
+                return nil, fmt.Errorf("sudoku: marking %d leaves the board at %v", i + 1, p)
+            }
+        }
+        if m.Cells[0] == m.Cells[1] {
+            return nil, fmt.Errorf("sudoku: marking %d relates %v to itself", i + 1, m.Cells[0])
+        }
+        r, ok := byName[m.Relation.Name]
+        if !ok {
+            r = &related{relation: m.Relation}
+            r.supportFirst, r.supportSecond = supportOf(m.Relation, geo.values())
+            byName[m.Relation.Name] = r
+            names = append(names, m.Relation.Name)
+        }
+        r.pairs = append(r.pairs, m.Cells)
+    }
+    sort.Strings(names)
+    constraints := []Constraint{}
+    for _, name := range names {
+        constraints = append(constraints, byName[name])
+    }
+    return geo.with(constraints...), nil
+}
+
+// A copy of the geometry in which no two cells next to each other in a
+// row or column hold consecutive values.
+func (geo *Geometry) WithNonConsecutive() *Geometry {
+    markings := []Marking{}
+    for _, pair := range geo.pairsOf(Orthogonal) {
+        markings = append(markings, Marking{NonConsecutive, pair})
+    }
+    out, _ := geo.WithMarkings(markings...)
+    return out
+}
+
+// The values each value keeps to the relation with: supportFirst[v] holds
+// every w for which v in the first cell and w in the second keep to it,
+// and supportSecond[v] every w for which w in the first and v in the
+// second do.
+func supportOf(relation Relation, values int) (supportFirst, supportSecond []Cell) {
+    supportFirst, supportSecond = make([]Cell, values + 1), make([]Cell, values + 1)
+    for v := 1; v <= values; v++ {
+        for w := 1; w <= values; w++ {
+            if relation.Holds(v, w) {
+                supportFirst[v] |= C(w)
+                supportSecond[w] |= C(v)
+            }
+        }
+    }
+    return supportFirst, supportSecond
+}
+
+// The pairs of cells marked with one relation. A candidate of either cell
+// goes once no candidate of the other keeps to the relation with it: the
+// pairs are kept arc consistent.
+type related struct {
+    relation Relation
+    pairs    [][2]Pos
+    // From supportOf, so Apply only needs bit operations.
+    supportFirst, supportSecond []Cell
+}
+
+func (r *related) Name() string {
+    return r.relation.Name
+}
+
+func (r *related) Difficulty() float64 {
+    return 1.7
+}
+
+func (r *related) Apply(g *Grid) Result {
+    result := Result{}
+    for i := range r.pairs {
+        a, b := r.pairs[i][0], r.pairs[i][1]
+        // Cells which share a unit can't hold the same value either.
+        differ := g.Sees(a, b)
+        outA := unsupported(g.At(a), g.At(b), r.supportFirst, differ)
+        outB := unsupported(g.At(b), g.At(a), r.supportSecond, differ)
+        if outA | outB == 0 {
+            continue
+        }
+        d := Deduction{
+            Technique: r.Name(),
+            Cells: r.pairs[i][:],
+            Values: g.At(a).difference(outA) | g.At(b).difference(outB),
+            Eliminations: []Elimination{{a, outA}, {b, outB}},
+        }
+        result.add(g, d)
+    }
+    return result
+}
+
+// The candidates of cell which no candidate of other supports, going by
+// support; the same value doesn't support itself if the cells differ.
+func unsupported(cell, other Cell, support []Cell, differ bool) Cell {
+    var out Cell
+    for rest := cell; rest != 0; rest &= rest - 1 {
+        v := rest.Value()
+        if v >= len(support) {
+            out |= bit(v)
+            continue
+        }
+        supported := support[v]
+        if differ {
+            supported = supported.remove(v)
+        }
+        if other & supported == 0 {
+            out |= bit(v)
+        }
+    }
+    return out
+}
+
+// A pair breaks its relation once both cells are solved with values which
+// don't keep to it.
+func (r *related) Check(g *Grid) error {
+    for i, pair := range r.pairs {
+        a, b := g.At(pair[0]), g.At(pair[1])
+        if a.IsSolved() && b.IsSolved() && !r.relation.Holds(a.Value(), b.Value()) {
+            return &ContradictionError{PairUnit, i, a.Value(), fmt.Sprintf("%v and %v are %d and %d, against the %s rule", pair[0], pair[1], a.Value(), b.Value(), strings.ToLower(r.relation.Name))}
+        }
+    }
+    return nil
+}
+
+// Parse markings written one to a line as a relation's name from
+// Relations followed by its two cells, as in "white r1c1 r1c2". Blank
+// lines and lines starting with '#' are skipped.
+func ParseMarkings(input string) ([]Marking, error) {
+    markings := []Marking{}
+    for i, line := range strings.Split(input, "\n") {
+        fields := fieldsOf(line, i + 1)
+        if len(fields) == 0 || strings.HasPrefix(fields[0].text, "#") {
+            continue
+        }
+        relation, ok := Relations[strings.ToLower(fields[0].text)]
+        if !ok {
+            return nil, &ParseError{i + 1, fields[0].col, fmt.Sprintf("there is no relation %q", fields[0].text)}
+        }
+        if len(fields) != 3 {
+            return nil, &ParseError{i + 1, 0, fmt.Sprintf("expected a relation and two cells, like white r1c1 r1c2, but found %d words", len(fields))}
+        }
+        m := Marking{Relation: relation}
+        for j, f := range fields[1:] {
+            p, err := parsePos(f)
+            if err != nil {
+                return nil, err
+            }
+            m.Cells[j] = p
+        }
+        markings = append(markings, m)
+    }
+    if len(markings) == 0 {
+        return nil, &ParseError{1, 0, "no markings found"}
+    }
+    return markings, nil
+}
+
+// Read a cell written as Pos.String writes it, like r3c5.
+func parsePos(f field) (Pos, error) {
+    var row, col int
+    text := strings.ToLower(f.text)
+    if _, err := fmt.Sscanf(text, "r%dc%d", &row, &col); err != nil || row < 1 || col < 1 || (Pos{row - 1, col - 1}).String() != text {
+        return Pos{}, &ParseError{f.line, f.col, fmt.Sprintf("expected a cell like r3c5, but found %q", f.text)}
+    }
+    return Pos{row - 1, col - 1}, nil
+}
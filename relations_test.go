@@ -0,0 +1,135 @@
+package sudoku
+
+import (
+    "context"
+    "errors"
+    "io/ioutil"
+    "testing"
+)
+
+func kropkiGeometry(t *testing.T) *Geometry {
+    text, err := ioutil.ReadFile("testdata/kropki.txt")
+    if err != nil {
+        t.Fatalf("Could not read the markings: %v", err)
+    }
+    markings, err := ParseMarkings(string(text))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    geo, err := StandardGeometry(9).WithMarkings(markings...)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    return geo
+}
+
+func TestRelations(t *testing.T) {
+    data := []struct {
+        relation Relation
+        a, b     int
+        holds    bool
+    }{
+        {NonConsecutive, 3, 5, true},
+        {NonConsecutive, 5, 4, false},
+        {WhiteDot, 5, 4, true},
+        {WhiteDot, 5, 3, false},
+        {BlackDot, 3, 6, true},
+        {BlackDot, 8, 4, true},
+        {BlackDot, 3, 5, false},
+        {XSum, 3, 7, true},
+        {XSum, 3, 6, false},
+        {VSum, 1, 4, true},
+        {VSum, 2, 4, false},
+    }
+    for _, datum := range data {
+        if datum.relation.Holds(datum.a, datum.b) != datum.holds {
+            t.Errorf("Expected %s to be %v for %d and %d", datum.relation.Name, datum.holds, datum.a, datum.b)
+        }
+    }
+}
+
+func TestParsesMarkings(t *testing.T) {
+    markings, err := ParseMarkings("# A comment\nwhite r1c1 r1c2\n\nX R2C1, r3c1\n")
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if len(markings) != 2 || markings[0].String() != "White dot: r1c1, r1c2" || markings[1].String() != "X: r2c1, r3c1" {
+        t.Errorf("Parsed the wrong markings: %v", markings)
+    }
+}
+
+func TestParseMarkingsSaysWhereTheInputIsWrong(t *testing.T) {
+    for input, expected := range map[string]string{
+        "white r1c1 r1c2\ngrey r1c1 r1c2": "sudoku: line 2, column 1: there is no relation \"grey\"",
+        "white r1c1": "sudoku: line 1: expected a relation and two cells, like white r1c1 r1c2, but found 2 words",
+        "black r1c1 r0c2": "sudoku: line 1, column 12: expected a cell like r3c5, but found \"r0c2\"",
+        "v r1c1 r1c2x": "sudoku: line 1, column 8: expected a cell like r3c5, but found \"r1c2x\"",
+        "# nothing": "sudoku: line 1: no markings found",
+    } {
+        _, err := ParseMarkings(input)
+        if err == nil || err.Error() != expected {
+            t.Errorf("For %q expected %q, but got %v", input, expected, err)
+        }
+    }
+}
+
+func TestWithMarkingsRejectsBadMarkings(t *testing.T) {
+    for _, m := range []Marking{
+        {WhiteDot, [2]Pos{{0, 0}, {0, 9}}},
+        {WhiteDot, [2]Pos{{3, 3}, {3, 3}}},
+    } {
+        if _, err := StandardGeometry(9).WithMarkings(m); err == nil {
+            t.Errorf("Expected an error for %v", m)
+        }
+    }
+}
+
+func TestMarkingsKeepPairsArcConsistent(t *testing.T) {
+    board := NewBoard(9)
+    board[0][1] = C(3)
+    geo, _ := StandardGeometry(9).WithMarkings(Marking{BlackDot, [2]Pos{{0, 0}, {0, 1}}}, Marking{XSum, [2]Pos{{4, 4}, {4, 5}}})
+    g, err := geo.NewGrid(board)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    // Only 6 is twice or half of 3.
+    r := geo.Constraints[0].Apply(g)
+    if len(r.Eliminations) != 1 || g.At(Pos{0, 0}).difference(r.Eliminations[0].Values) != C(6) {
+        t.Errorf("Expected r1c1 to be left with 6, but got %v", r.Deductions)
+    }
+    // Two cells of a row can't both be 5.
+    r = geo.Constraints[1].Apply(g)
+    for _, e := range r.Eliminations {
+        if e.Values != C(5) {
+            t.Errorf("Expected only 5 to go, but got %v", r.Deductions)
+        }
+    }
+    if len(r.Eliminations) != 2 {
+        t.Errorf("Expected 5 to go from both cells, but got %v", r.Deductions)
+    }
+}
+
+func TestSolvesKropkiWithoutGivens(t *testing.T) {
+    geo := kropkiGeometry(t)
+    solution, err := geo.Solve(context.Background(), NewBoard(9))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := solution.Equals(solved); !same {
+        t.Errorf("Unexpected solution: %v", msg)
+    }
+}
+
+func TestReportsContradictionsInMarkings(t *testing.T) {
+    board := NewBoard(9)
+    board[0][0], board[0][1] = C(4), C(5)
+    _, err := StandardGeometry(9).WithNonConsecutive().NewGrid(board)
+    var contradiction *ContradictionError
+    if !errors.As(err, &contradiction) || contradiction.Unit != PairUnit || contradiction.Value != 4 {
+        t.Errorf("Expected a contradiction in a pair, over the 4, but got %v", err)
+    }
+    board[0][1] = C(6)
+    if _, err := StandardGeometry(9).WithNonConsecutive().NewGrid(board); err != nil {
+        t.Errorf("Unexpected error %v", err)
+    }
+}
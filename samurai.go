@@ -0,0 +1,262 @@
+package sudoku
+
+import (
+    "context"
+    "fmt"
+    "strings"
+)
+
+// A Samurai puzzle's board is 21 cells on a side, with gaps between its
+// outer grids.
+const samuraiSize = 21
+
+// The top left cell of each of a Samurai's grids on its board, in the
+// order of Samurai: top left, top right, centre, bottom left and bottom
+// right.
+var samuraiCorners = [5]Pos{{0, 0}, {0, 12}, {6, 6}, {12, 0}, {12, 12}}
+
+// A Samurai puzzle: five 9x9 grids in a cross, the centre one sharing a
+// corner box with each of the other four. Each grid is an ordinary board;
+// the cells in a shared box must be the same in both grids.
+type Samurai [5]Board
+
+// The geometry of the whole cross on one 21x21 board, as Samurai.Board
+// lays it out. The units of grid i are numbered from 9 * i; the boxes
+// the centre grid shares are only counted once, as the outer grid's.
+// Cells between the grids are in no unit, and always empty.
+func SamuraiGeometry() *Geometry {
+    geo := &Geometry{Size: samuraiSize, Values: 9}
+    boxes := map[Pos]bool{}
+    for i, corner := range samuraiCorners {
+        for _, u := range StandardGeometry(9).Units {
+            if u.Kind == Square {
+                top := Pos{corner.Row + u.Cells[0].Row, corner.Col + u.Cells[0].Col}
+                if boxes[top] {
+                    continue
+                }
+                boxes[top] = true
+            }
+            cells := make([]Pos, len(u.Cells))
+            for j, p := range u.Cells {
+                cells[j] = Pos{corner.Row + p.Row, corner.Col + p.Col}
+            }
+            geo.Units = append(geo.Units, Unit{u.Kind, 9 * i + u.Index, cells})
+        }
+    }
+    return geo
+}
+
+// The grids laid out on one 21x21 board, with the cells between them
+// blank. Returns an error if a grid isn't 9x9, or two grids give different
+// values for a cell they share.
+func (s Samurai) Board() (Board, error) {
+    board := NewBoard(samuraiSize)
+    for i, grid := range s {
+        if len(grid) != 9 {
+            return nil, fmt.Errorf("sudoku: grid %d of the Samurai has %d rows, but needs 9", i + 1, len(grid))
+        }
+        corner := samuraiCorners[i]
+        for r, row := range grid {
+            if len(row) != 9 {
+                return nil, fmt.Errorf("sudoku: row %d of grid %d of the Samurai has %d cells, but needs 9", r + 1, i + 1, len(row))
+            }
+            for c, cell := range row {
+                p := Pos{corner.Row + r, corner.Col + c}
+                here := &board[p.Row][p.Col]
+                if !here.isEmpty() && !cell.isEmpty() && *here != cell {
+                    return nil, fmt.Errorf("sudoku: the Samurai's grids disagree about %v", p)
+                }
+                if !cell.isEmpty() {
+                    *here = cell
+                }
+            }
+        }
+    }
+    return board, nil
+}
+
+// The five grids of a 21x21 board laid out as Samurai.Board does.
+func samuraiOf(board Board) Samurai {
+    var s Samurai
+    for i, corner := range samuraiCorners {
+        s[i] = NewBoard(9)
+        for r := range s[i] {
+            for c := range s[i][r] {
+                s[i][r][c] = board[corner.Row + r][corner.Col + c]
+            }
+        }
+    }
+    return s
+}
+
+// Report whether the grids fit together and break none of the rules,
+// with the errors of Samurai.Board and Geometry.NewGrid.
+func (s Samurai) Validate() error {
+    board, err := s.Board()
+    if err != nil {
+        return err
+    }
+    _, err = SamuraiGeometry().NewGrid(board)
+    return err
+}
+
+// Solve the whole puzzle at once, so what is deduced in a shared box
+// carries over into both its grids. The errors are those of Board.SolveE,
+// or Samurai.Board's if the grids don't fit together.
+func (s Samurai) Solve(ctx context.Context) (Samurai, error) {
+    board, err := s.Board()
+    if err != nil {
+        return Samurai{}, err
+    }
+    solution, err := SamuraiGeometry().Solve(ctx, board)
+    if solution == nil {
+        return Samurai{}, err
+    }
+    return samuraiOf(solution), err
+}
+
+// Count the puzzle's solutions, as Board.CountSolutions does; 0 if the
+// grids don't fit together.
+func (s Samurai) CountSolutions(limit int) int {
+    board, err := s.Board()
+    if err != nil {
+        return 0
+    }
+    return SamuraiGeometry().CountSolutions(board, limit)
+}
+
+// Is the cell one of a grid's, rather than in a gap between them?
+func inSamurai(row, col int) bool {
+    for _, corner := range samuraiCorners {
+        if row >= corner.Row && row < corner.Row + 9 && col >= corner.Col && col < corner.Col + 9 {
+            return true
+        }
+    }
+    return false
+}
+
+// The cross as 21 lines of 21 cells, as ParseSamurai reads it: '.' for
+// cells that aren't solved and spaces in the gaps between the grids.
+func (s Samurai) String() string {
+    board, err := s.Board()
+    if err != nil {
+        return err.Error()
+    }
+    lines := make([]string, samuraiSize)
+    for r, row := range board {
+        line := make([]rune, samuraiSize)
+        for c, cell := range row {
+            switch {
+                case !inSamurai(r, c):
+                    line[c] = ' '
+                case cell.IsSolved():
+                    line[c] = Digits.symbolFor(cell.Value())
+                default:
+                    line[c] = '.'
+            }
+        }
+        lines[r] = strings.TrimRight(string(line), " ")
+    }
+    return strings.Join(lines, "\n") + "\n"
+}
+
+// Like Board.GoString: the cross with its boxes marked out.
+func (s Samurai) GoString() string {
+    board, err := s.Board()
+    if err != nil {
+        return err.Error()
+    }
+    // Draw leaves the gaps empty, but marks off the boxes beside them, and
+    // rules the bottom of the board right across.
+    lines := strings.Split(Digits.Draw(board, SamuraiGeometry()), "\n")
+    r := -1
+    for i, line := range lines {
+        ruled := strings.Contains(line, "-")
+        if !ruled {
+            r++
+        }
+        if line == "" || r >= samuraiSize {
+            continue
+        }
+        cells := []byte(line + strings.Repeat(" ", 2 * samuraiSize - len(line)))
+        for c := 0; c < samuraiSize; c++ {
+            gap := !inSamurai(r, c)
+            if ruled {
+                gap = gap && (r + 1 == samuraiSize || !inSamurai(r + 1, c))
+            }
+            if gap {
+                cells[2 * c], cells[2 * c + 1] = ' ', ' '
+            }
+        }
+        lines[i] = strings.TrimRight(string(cells), " ")
+    }
+    return strings.Join(lines, "\n")
+}
+
+// Parse a Samurai written either as its five grids, top left, top right,
+// centre, bottom left then bottom right, each on one line as Parse reads
+// them, or as the whole cross, as Samurai.String writes it: 21 lines of
+// 21 cells, where each character stands for the cell in its column. The
+// gaps between the grids may hold spaces, '.' or '#', or be left off the
+// end of a line.
+func ParseSamurai(input string) (Samurai, error) {
+    type line struct {
+        text   string
+        number int
+    }
+    lines := []line{}
+    for i, text := range strings.Split(input, "\n") {
+        text = strings.TrimRight(text, " \t\r")
+        if text != "" {
+            lines = append(lines, line{text, i + 1})
+        }
+    }
+
+    var s Samurai
+    switch len(lines) {
+        case 5:
+            for i, l := range lines {
+                grid, err := Parse(l.text)
+                if err != nil {
+                    if e, ok := err.(*ParseError); ok {
+                        e.Line = l.number
+                    }
+                    return Samurai{}, err
+                }
+                if len(grid) != 9 {
+                    return Samurai{}, &ParseError{l.number, 0, fmt.Sprintf("grid %d is %d-by-%d, but needs to be 9-by-9", i + 1, len(grid), len(grid))}
+                }
+                s[i] = grid
+            }
+        case samuraiSize:
+            board := NewBoard(samuraiSize)
+            for r, l := range lines {
+                runes := []rune(l.text)
+                for c := 0; c < samuraiSize || c < len(runes); c++ {
+                    if !inSamurai(r, c) {
+                        if c < len(runes) && !strings.ContainsRune(" .#", runes[c]) {
+                            return Samurai{}, &ParseError{l.number, c + 1, fmt.Sprintf("%q is between the grids", runes[c])}
+                        }
+                        continue
+                    }
+                    if c >= len(runes) {
+                        return Samurai{}, &ParseError{l.number, 0, fmt.Sprintf("expected a cell in column %d, but the line ends", c + 1)}
+                    }
+                    v, err := Digits.valueOf(symbol{runes[c], l.number, c + 1}, 9)
+                    if err != nil {
+                        return Samurai{}, err
+                    }
+                    board[r][c] = C(v)
+                }
+            }
+            s = samuraiOf(board)
+        case 0:
+            return Samurai{}, &ParseError{1, 0, "no Samurai found"}
+        default:
+            return Samurai{}, &ParseError{lines[0].number, 0, fmt.Sprintf("expected 5 grids or 21 rows, but found %d lines", len(lines))}
+    }
+    if _, err := s.Board(); err != nil {
+        return Samurai{}, err
+    }
+    return s, nil
+}
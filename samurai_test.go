@@ -0,0 +1,149 @@
+package sudoku
+
+import (
+    "context"
+    "errors"
+    "io/ioutil"
+    "strings"
+    "testing"
+)
+
+// The top left and centre grids of the solution of testdata/samurai.txt.
+const samuraiTopLeft = "921354867743896215586127943472963581839215476165748392394571628657482139218639754"
+const samuraiCentre = "628493751139275684754186329983621475476358192215749863891564237362917548547832916"
+
+func readSamurai(t *testing.T) (string, Samurai) {
+    text, err := ioutil.ReadFile("testdata/samurai.txt")
+    if err != nil {
+        t.Fatalf("Could not read the puzzle: %v", err)
+    }
+    s, err := ParseSamurai(string(text))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    return string(text), s
+}
+
+func TestSamuraiGeometryHasOneUnitPerRowColumnAndBox(t *testing.T) {
+    geo := SamuraiGeometry()
+    counts := map[UnitKind]int{}
+    for _, u := range geo.Units {
+        counts[u.Kind]++
+        if len(u.Cells) != 9 {
+            t.Errorf("%v has %d cells", u, len(u.Cells))
+        }
+    }
+    // The centre grid's corner boxes belong to the outer grids.
+    if counts[Row] != 45 || counts[Column] != 45 || counts[Square] != 41 {
+        t.Errorf("Expected 45 rows, 45 columns and 41 boxes, but got %v", counts)
+    }
+}
+
+func TestSamuraiStringRoundTrips(t *testing.T) {
+    text, s := readSamurai(t)
+    if s.String() != text {
+        t.Errorf("Expected\n%s\nbut got\n%s", text, s.String())
+    }
+}
+
+func TestParseSamuraiReadsFiveGrids(t *testing.T) {
+    _, s := readSamurai(t)
+    lines := []string{}
+    for _, grid := range s {
+        lines = append(lines, grid.String())
+    }
+    other, err := ParseSamurai(strings.Join(lines, "\n"))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if other.String() != s.String() {
+        t.Errorf("Expected\n%s\nbut got\n%s", s, other)
+    }
+}
+
+func TestSolvesSamurai(t *testing.T) {
+    _, s := readSamurai(t)
+    solution, err := s.Solve(context.Background())
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if solution[0].String() != samuraiTopLeft || solution[2].String() != samuraiCentre {
+        t.Errorf("Unexpected solution\n%s", solution)
+    }
+    // The bottom right box of the top left grid is the centre's top left.
+    for r := 0; r < 3; r++ {
+        for c := 0; c < 3; c++ {
+            if solution[0][6 + r][6 + c] != solution[2][r][c] {
+                t.Errorf("The grids disagree about r%dc%d of the centre", r + 1, c + 1)
+            }
+        }
+    }
+    if err := solution.Validate(); err != nil {
+        t.Errorf("Unexpected error %v", err)
+    }
+    if count := s.CountSolutions(2); count != 1 {
+        t.Errorf("Expected 1 solution, but found %d", count)
+    }
+}
+
+func TestSharedCellsCarryBetweenGrids(t *testing.T) {
+    _, s := readSamurai(t)
+    // The centre grid alone has many solutions; the others pin it down.
+    if count := s[2].CountSolutions(2); count != 2 {
+        t.Errorf("Expected the centre to have many solutions, but found %d", count)
+    }
+    solution, _ := s.Solve(context.Background())
+    if solution[2].String() != samuraiCentre {
+        t.Errorf("Expected %s, but got %s", samuraiCentre, solution[2])
+    }
+}
+
+func TestSamuraiGridsMustAgree(t *testing.T) {
+    _, s := readSamurai(t)
+    s[2] = s[2].clone()
+    s[2][0][0] = C(9)
+    if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "disagree about r7c7") {
+        t.Errorf("Expected the grids to disagree, but got %v", err)
+    }
+    if _, err := s.Solve(context.Background()); err == nil {
+        t.Errorf("Expected an error")
+    }
+}
+
+func TestSamuraiValidateFindsContradictions(t *testing.T) {
+    _, s := readSamurai(t)
+    s[4] = s[4].clone()
+    // 5 is already in the last grid's first row.
+    s[4][0][0] = C(5)
+    if err := s.Validate(); !errors.Is(err, ErrContradiction) {
+        t.Errorf("Expected a contradiction, but got %v", err)
+    }
+}
+
+func TestSamuraiGoStringLeavesTheGapsEmpty(t *testing.T) {
+    _, s := readSamurai(t)
+    lines := strings.Split(s.GoString(), "\n")
+    // Row 10, below the separator under the third row of boxes.
+    if !strings.HasPrefix(lines[12], strings.Repeat(" ", 12)) {
+        t.Errorf("Expected the gap left of the centre grid, but got %q", lines[12])
+    }
+}
+
+func TestParseSamuraiErrors(t *testing.T) {
+    _, s := readSamurai(t)
+    lines := strings.Split(s.String(), "\n")
+    lines[10] = "     X" + lines[10][6:]
+    _, err := ParseSamurai(strings.Join(lines, "\n"))
+    if e, ok := err.(*ParseError); !ok || e.Line != 11 || e.Col != 6 {
+        t.Errorf("Expected an error at line 11, column 6, but got %v", err)
+    }
+    lines = strings.Split(s.String(), "\n")
+    lines[0] = "A" + lines[0][1:]
+    _, err = ParseSamurai(strings.Join(lines, "\n"))
+    if e, ok := err.(*ParseError); !ok || e.Line != 1 || e.Col != 1 {
+        t.Errorf("Expected an error at line 1, column 1, but got %v", err)
+    }
+    if _, err := ParseSamurai("123\n456\n"); err == nil {
+        t.Errorf("Expected an error")
+    }
+}
@@ -0,0 +1,97 @@
+package sudoku
+
+import (
+    "context"
+    "fmt"
+    "math/rand"
+)
+
+// Find what, if anything, stops a set from being completed: an empty cell,
+// a value solved in two cells, a value out of range or a value with nowhere
+// left to go. Returns the offending value and why; why is "" if the set is fine.
+func conflictIn(set Set) (int, string) {
+    all := Create(len(set))
+    solved, possible := C(), C()
+    for _, cell := range set {
+        if cell.isEmpty() {
+            return 0, "a cell has no candidates left"
+        }
+        if out := cell.difference(all); out != 0 {
+            return out.Value(), fmt.Sprintf("%d is out of range", out.Value())
+        }
+        possible |= cell
+        if cell.IsSolved() {
+            if solved & cell != 0 {
+                return cell.Value(), fmt.Sprintf("%d appears more than once", cell.Value())
+            }
+            solved |= cell
+        }
+    }
+    if missing := all.difference(possible); missing != 0 {
+        return missing.Value(), fmt.Sprintf("%d has nowhere to go", missing.Value())
+    }
+    return 0, ""
+}
+
+// The strategies Solve and friends propagate with between guesses: the
+// singles, which between them do what ConstrainSet does, then box-line
+// reduction.
+var defaultSolver = NewSolver(HiddenSingle, NakedSingle, LockedCandidates)
+
+// Find the unsolved cell with the fewest remaining candidates, leaving
+// out cells which aren't in the puzzle.
+func (g *Grid) fewestCandidates() (int, int) {
+    row, col, fewest := -1, -1, 0
+    for i := range g.Board {
+        for j, cell := range g.Board[i] {
+            if g.inPuzzle(i, j) && !cell.IsSolved() && (row < 0 || cell.Len() < fewest) {
+                row, col, fewest = i, j, cell.Len()
+            }
+        }
+    }
+    return row, col
+}
+
+// Call visit with each solution of the board, until it returns false. When
+// rng isn't nil the guesses for each cell are tried in a random order.
+// Returns a *ContradictionError if the givens break a unit, or the
+// context's error if it is done first.
+func (s *Solver) each(ctx context.Context, board Board, rng *rand.Rand, visit func(Board) bool) error {
+    g, err := s.newGrid(board)
+    if err != nil {
+        return err
+    }
+    _, err = s.search(ctx, g, rng, visit)
+    return err
+}
+
+// Depth-first search: propagate, then guess each candidate of the most
+// constrained cell in turn. visit is called with every solution found;
+// returning false from it stops the search, as does the context being
+// done, whose error is then returned.
+func (s *Solver) search(ctx context.Context, g *Grid, rng *rand.Rand, visit func(Board) bool) (bool, error) {
+    if err := s.Propagate(ctx, g); err != nil {
+        if _, ok := err.(*ContradictionError); ok {
+            return true, nil
+        }
+        return false, err
+    }
+    if g.solved() {
+        return visit(g.Board), nil
+    }
+    row, col := g.fewestCandidates()
+    guesses := g.Board[row][col].Values()
+    if rng != nil {
+        rng.Shuffle(len(guesses), func(i, j int) {
+            guesses[i], guesses[j] = guesses[j], guesses[i]
+        })
+    }
+    for _, v := range guesses {
+        guess := g.clone()
+        guess.place(Pos{row, col}, v)
+        if more, err := s.search(ctx, guess, rng, visit); !more || err != nil {
+            return false, err
+        }
+    }
+    return true, nil
+}
@@ -1,3 +1,99 @@
 package sudoku
 
+import (
+    "math/bits"
+)
+
 type Set []Cell
+
+// How many cells of the set could hold v.
+func (s Set) count(v int) int {
+    n := 0
+    for _, cell := range s {
+        if cell.Has(v) {
+            n++
+        }
+    }
+    return n
+}
+
+// Some cells of a set, by index, and the values between them.
+type subset struct {
+    cells  []int
+    values Cell
+}
+
+// Every naked subset of the set: size unsolved cells whose candidates
+// between them are only size values.
+func (s Set) nakedSubsets(size int) []subset {
+    open := []int{}
+    for i, cell := range s {
+        if n := cell.Len(); n >= 2 && n <= size {
+            open = append(open, i)
+        }
+    }
+    found := []subset{}
+    combinations(len(open), size, func(pick []int) bool {
+        sub := subset{}
+        for _, i := range pick {
+            sub.cells = append(sub.cells, open[i])
+            sub.values |= s[open[i]]
+        }
+        if sub.values.Len() == size {
+            found = append(found, sub)
+        }
+        return true
+    })
+    return found
+}
+
+// Every hidden subset of the set: size values which, between them, can
+// only go in the same size cells.
+func (s Set) hiddenSubsets(size int) []subset {
+    var all Cell
+    for _, cell := range s {
+        all |= cell
+    }
+    values := []int{}
+    places := map[int]uint64{}
+    for _, v := range all.Values() {
+        for i, cell := range s {
+            if cell.Has(v) {
+                places[v] |= 1 << uint(i)
+            }
+        }
+        if n := bits.OnesCount64(places[v]); n >= 2 && n <= size {
+            values = append(values, v)
+        }
+    }
+    found := []subset{}
+    combinations(len(values), size, func(pick []int) bool {
+        sub := subset{}
+        in := uint64(0)
+        for _, i := range pick {
+            sub.values |= bit(values[i])
+            in |= places[values[i]]
+        }
+        if bits.OnesCount64(in) != size {
+            return true
+        }
+        for i := range s {
+            if in & (1 << uint(i)) != 0 {
+                sub.cells = append(sub.cells, i)
+            }
+        }
+        found = append(found, sub)
+        return true
+    })
+    return found
+}
+
+// Whether the subset has the cell at index i.
+func (sub subset) has(i int) bool {
+    for _, j := range sub.cells {
+        if i == j {
+            return true
+        }
+    }
+    return false
+}
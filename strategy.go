@@ -0,0 +1,291 @@
+package sudoku
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// A deduction technique. Apply looks at the grid, without changing it, and
+// returns everything the technique can deduce from it. Difficulty rates the
+// technique on the same scale as Sudoku Explainer.
+type Strategy interface {
+    Name() string
+    Difficulty() float64
+    Apply(g *Grid) Result
+}
+
+// A value which must go in a cell.
+type Placement struct {
+    Pos
+    Value int
+}
+
+// Candidates which can't go in a cell.
+type Elimination struct {
+    Pos
+    Values Cell
+}
+
+// A rule for some of a puzzle's cells besides each unit holding every
+// value once, like the sum of a killer cage. It makes deductions as a
+// Strategy does, and Check reports, as a *ContradictionError, when the
+// cells already solved break it.
+type Constraint interface {
+    Strategy
+    Check(g *Grid) error
+}
+
+// One deduction: the technique that made it, the unit it was made in (nil
+// if it took more than one), the cells and values it rests on, and what it
+// placed or took away.
+type Deduction struct {
+    Technique    string
+    Unit         *Unit
+    Cells        []Pos
+    Values       Cell
+    Placements   []Placement
+    Eliminations []Elimination
+    // The rows or columns a fish is found in, those covering them, and the
+    // places of its base which the cover misses.
+    Base, Cover  []Unit
+    Fins         []Pos
+}
+
+// Explain the deduction, as in "Hidden single: 7 in row 3 can only go in r3c5".
+func (d Deduction) String() string {
+    if len(d.Placements) == 1 && len(d.Eliminations) == 0 {
+        p := d.Placements[0]
+        if d.Unit != nil {
+            return fmt.Sprintf("%s: %d in %v can only go in %v", d.Technique, p.Value, d.Unit, p.Pos)
+        }
+        return fmt.Sprintf("%s: %v is %d", d.Technique, p.Pos, p.Value)
+    }
+
+    out := d.Technique + ":"
+    if len(d.Base) > 0 {
+        out += fmt.Sprintf(" %s in %s, covered by %s", valueList(d.Values, ", "), unitList(d.Base), unitList(d.Cover))
+        if len(d.Fins) > 0 {
+            out += fmt.Sprintf(", with fins %s", posList(d.Fins))
+        }
+    } else if d.Values != 0 && len(d.Cells) > 0 {
+        out += fmt.Sprintf(" %s in %s", valueList(d.Values, ", "), posList(d.Cells))
+    }
+    if d.Unit != nil {
+        out += fmt.Sprintf(" in %v", d.Unit)
+    }
+    consequences := []string{}
+    for _, p := range d.Placements {
+        consequences = append(consequences, fmt.Sprintf("%v is %d", p.Pos, p.Value))
+    }
+    for _, e := range d.Eliminations {
+        consequences = append(consequences, fmt.Sprintf("%v can't be %s", e.Pos, valueList(e.Values, " or ")))
+    }
+    return out + ", so " + strings.Join(consequences, "; ")
+}
+
+func valueList(values Cell, sep string) string {
+    out := []string{}
+    for _, v := range values.Values() {
+        out = append(out, strconv.Itoa(v))
+    }
+    return strings.Join(out, sep)
+}
+
+// Units of one kind, as in "rows 1, 5".
+func unitList(units []Unit) string {
+    out := []string{}
+    for _, u := range units {
+        out = append(out, strconv.Itoa(u.Index + 1))
+    }
+    return fmt.Sprintf("%vs %s", units[0].Kind, strings.Join(out, ", "))
+}
+
+func posList(cells []Pos) string {
+    out := []string{}
+    for _, p := range cells {
+        out = append(out, p.String())
+    }
+    return strings.Join(out, ", ")
+}
+
+// What a strategy deduced: every placement and elimination, and the
+// deductions which made them.
+type Result struct {
+    Placements   []Placement
+    Eliminations []Elimination
+    Deductions   []Deduction
+}
+
+func (r Result) IsEmpty() bool {
+    return len(r.Placements) == 0 && len(r.Eliminations) == 0
+}
+
+// Record a deduction, leaving out eliminations of candidates which are
+// already gone, and the deduction itself if that leaves it doing nothing.
+func (r *Result) add(g *Grid, d Deduction) {
+    eliminations := []Elimination{}
+    for _, e := range d.Eliminations {
+        if e.Values &= g.At(e.Pos); e.Values != 0 {
+            eliminations = append(eliminations, e)
+        }
+    }
+    d.Eliminations = eliminations
+    if len(d.Placements) == 0 && len(d.Eliminations) == 0 {
+        return
+    }
+    r.Placements = append(r.Placements, d.Placements...)
+    r.Eliminations = append(r.Eliminations, d.Eliminations...)
+    r.Deductions = append(r.Deductions, d)
+}
+
+type unitFilter struct {
+    name       string
+    difficulty float64
+    filter     func(Set) Set
+}
+
+// A Strategy which runs a filter over every unit of the grid, the way
+// Board.Step does, and reports what it changed. Cells the filter narrows
+// to one value are placements, anything else it takes away eliminations.
+func UnitFilter(name string, difficulty float64, filter func(Set) Set) Strategy {
+    return &unitFilter{name, difficulty, filter}
+}
+
+func (f unitFilter) Name() string {
+    return f.name
+}
+
+func (f unitFilter) Difficulty() float64 {
+    return f.difficulty
+}
+
+func (f unitFilter) Apply(g *Grid) Result {
+    var r Result
+    var before, work Set
+    for i := range g.Units {
+        u := &g.Units[i]
+        before = g.setInto(before, *u)
+        work = append(work[:0], before...)
+        after := f.filter(work)
+        eliminated := Deduction{Technique: f.name, Unit: u}
+        for j, p := range u.Cells {
+            if !before[j].IsSolved() && after[j].IsSolved() && before[j].Has(after[j].Value()) {
+                r.add(g, Deduction{
+                    Technique: f.name,
+                    Unit: u,
+                    Cells: []Pos{p},
+                    Values: after[j],
+                    Placements: []Placement{{p, after[j].Value()}},
+                })
+            } else if removed := before[j].difference(after[j]); removed != 0 {
+                eliminated.Values |= removed
+                eliminated.Eliminations = append(eliminated.Eliminations, Elimination{p, removed})
+            }
+        }
+        // Blame the eliminations on whichever solved cells hold the values.
+        for j, p := range u.Cells {
+            if before[j].IsSolved() && before[j] & eliminated.Values != 0 {
+                eliminated.Cells = append(eliminated.Cells, p)
+            }
+        }
+        r.add(g, eliminated)
+    }
+    return r
+}
+
+// Runs an ordered list of strategies, always going back to the first one
+// once any of them makes progress, so the easiest deduction is made first.
+type Solver struct {
+    Strategies []Strategy
+    // The shape of the boards to solve; nil means an ordinary board.
+    Geometry *Geometry
+}
+
+func NewSolver(strategies ...Strategy) *Solver {
+    return &Solver{strategies, nil}
+}
+
+func (s *Solver) newGrid(board Board) (*Grid, error) {
+    if s.Geometry == nil {
+        return NewGrid(board)
+    }
+    return s.Geometry.NewGrid(board)
+}
+
+// Apply the first strategy which changes the grid, returning it and what it
+// deduced. The grid's constraints are tried along with the strategies,
+// each before the first strategy harder than it. Returns a nil Strategy if
+// none of them could make progress.
+func (s *Solver) Step(g *Grid) (Strategy, Result) {
+    constraints := g.Constraints
+    try := func(strategy Strategy) (Result, bool) {
+        r := strategy.Apply(g)
+        return r, !r.IsEmpty() && g.Apply(r)
+    }
+    for _, strategy := range s.Strategies {
+        for len(constraints) > 0 && constraints[0].Difficulty() <= strategy.Difficulty() {
+            if r, changed := try(constraints[0]); changed {
+                return constraints[0], r
+            }
+            constraints = constraints[1:]
+        }
+        if r, changed := try(strategy); changed {
+            return strategy, r
+        }
+    }
+    for _, constraint := range constraints {
+        if r, changed := try(constraint); changed {
+            return constraint, r
+        }
+    }
+    return nil, Result{}
+}
+
+// Step the grid until it is solved or no strategy makes progress. Returns
+// a *ContradictionError if the grid turns out to be impossible, or the
+// context's error if it is done first.
+func (s *Solver) Propagate(ctx context.Context, g *Grid) error {
+    for {
+        if err := g.conflict(); err != nil {
+            return err
+        }
+        if g.solved() {
+            return nil
+        }
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        if strategy, _ := s.Step(g); strategy == nil {
+            return nil
+        }
+    }
+}
+
+// Solve the board with the strategies, searching when they get stuck. The
+// errors are the same as for Board.SolveE.
+func (s *Solver) Solve(ctx context.Context, board Board) (Board, error) {
+    g, err := s.newGrid(board)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.Propagate(ctx, g); err != nil {
+        return nil, err
+    }
+
+    var solutions []Board
+    _, err = s.search(ctx, g, nil, func(b Board) bool {
+        solutions = append(solutions, b)
+        return len(solutions) < 2
+    })
+    switch {
+        case err != nil:
+            return nil, err
+        case len(solutions) == 0:
+            return nil, ErrNoProgress
+        case len(solutions) > 1:
+            return solutions[0], ErrMultipleSolutions
+    }
+    return solutions[0], nil
+}
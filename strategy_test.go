@@ -0,0 +1,59 @@
+package sudoku
+
+import (
+    "context"
+    "testing"
+)
+
+type countingStrategy struct {
+    Strategy
+    calls *int
+}
+
+func (s countingStrategy) Apply(g *Grid) Result {
+    *s.calls++
+    return s.Strategy.Apply(g)
+}
+
+func TestUnitFilterReportsPlacementsAndEliminations(t *testing.T) {
+    g := gridWithRow(Set{C(1,2), C(1,2), C(1,2,3), C(3,4)})
+
+    r := HiddenSingle.Apply(g)
+
+    if len(r.Placements) == 0 || r.Placements[0] != (Placement{Pos{0, 3}, 4}) {
+        t.Errorf("Expected 4 to be placed in r1c4, but got %+v", r.Placements)
+    }
+    g.Apply(r)
+    if !IsExactly(g.Board[0][2], C(1,2,3)) {
+        t.Errorf("Expected r1c3 to keep its candidates, but it is now %v", g.Board[0][2])
+    }
+
+    r = NakedSingle.Apply(g)
+    if r.IsEmpty() || !g.Apply(r) || !IsExactly(g.Board[0][2], C(3)) {
+        t.Errorf("Expected r1c3 to be left with 3, but it is now %v (%+v)", g.Board[0][2], r)
+    }
+}
+
+func TestSolverGoesBackToTheFirstStrategyAfterProgress(t *testing.T) {
+    singles, pairs := 0, 0
+    solver := NewSolver(countingStrategy{HiddenSingle, &singles}, countingStrategy{NakedPair, &pairs})
+    g := gridWithRow(Set{C(1,2), C(1,2), C(1,2,3,4), C(1,2,3,4)})
+
+    if strategy, _ := solver.Step(g); strategy == nil || strategy.Name() != "Naked pair" {
+        t.Errorf("Expected the naked pair to make progress, but got %v", strategy)
+    }
+    solver.Step(g)
+    if singles != 2 || pairs != 2 {
+        t.Errorf("Expected hidden singles to be tried again before naked pairs, but got %v and %v calls", singles, pairs)
+    }
+}
+
+func TestSolverSolvesWithItsStrategies(t *testing.T) {
+    solution, err := NewSolver(Strategies...).Solve(context.Background(), unsolved)
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if same, msg := solution.Equals(solved); !same {
+        t.Errorf("Wrong solution: %v", msg)
+    }
+}
@@ -1,53 +1,41 @@
 package sudoku
 
 import (
+    "context"
     "fmt"
     "math"
+    "math/bits"
+    "strings"
 )
 
 
 // Insert into a set (row/column/square) any of the values which aren't known to be in that set.
 func findMissingValues(set Set) Cell {
-    found := make(Cell, len(set))
+    found := C()
     for _, v := range set {
-        if len(v) == 1 {
-            found[v[0]-1] = v[0]
+        if v.IsSolved() {
+            found |= v
         }
     }
-
-    notFound := make(Cell, 0)
-    for i, v := range found {
-        if v == 0 {
-            notFound = append(notFound, i + 1)
-        }
-    }
-    return notFound
+    return Create(len(set)).difference(found)
 }
 
-// This gets rewritten when Cell is rewritten as bit-values on an int
-func oneOffsetComplementOf(orig []int, max int) []int {
-    compl := []int{}
-    for i := 1; i <= max; i++ {
-        found := false
-        for _, v := range orig {
-            if i == v {
-                found = true
-            }
-        }
-        if !found {
-            compl = append(compl, i)
-        }
-    }
-    return compl
+func oneOffsetComplementOf(orig Cell, max int) Cell {
+    return Create(max).difference(orig)
 }
 
+// The indexes from 0 to max-1 which aren't in orig, in order.
 func zeroOffsetComplementOf(orig []int, max int) []int {
+    in := make([]bool, max)
+    for _, v := range orig {
+        if v >= 0 && v < max {
+            in[v] = true
+        }
+    }
     compl := []int{}
     for i := 0; i < max; i++ {
-        for _, v := range orig {
-            if i != v {
-                compl = append(compl, i)
-            }
+        if !in[i] {
+            compl = append(compl, i)
         }
     }
     return compl
@@ -60,7 +48,7 @@ func constrainForSet(s Set, indexesInComplement []int, constrained int) Set {
     return s
 }
 
-func findMissingFor(input Set, onlyIn []int) []int {
+func findMissingFor(input Set, onlyIn []int) Cell {
     max := len(input)
     found := C()
     for _, v := range onlyIn {
@@ -70,61 +58,91 @@ func findMissingFor(input Set, onlyIn []int) []int {
     return missing
 }
 
-func ConstrainLinearAndSquare(input []Set, intersection []int) []Set {
-    indexesInComplement := zeroOffsetComplementOf(intersection, len(input))
-    constraineds := findMissingFor(input[1], intersection)
-    for _, constrained := range constraineds {
-        input[0] = constrainForSet(input[0], indexesInComplement, constrained)
+// Values which can only go in the cells of from at the given indexes
+// can't go anywhere in to but the cells at its own indexes.
+func lockIn(from Set, fromIndexes []int, to Set, toIndexes []int) {
+    locked := findMissingFor(from, zeroOffsetComplementOf(fromIndexes, len(from)))
+    outside := zeroOffsetComplementOf(toIndexes, len(to))
+    for _, v := range locked.Values() {
+        constrainForSet(to, outside, v)
     }
+}
+
+// Box-line reduction between a square, input[0], and a line which crosses
+// it, input[1]. inSquare and inLine are the indexes of the cells they
+// share, in each of them. A value which can only go in the shared cells of
+// the square can't go elsewhere in the line (pointing), and one which can
+// only go in those of the line can't go elsewhere in the square
+// (claiming). The sets are changed in place, and returned.
+func ConstrainLinearAndSquare(input []Set, inSquare, inLine []int) []Set {
+    square, line := input[0], input[1]
+    lockIn(line, inLine, square, inSquare)
+    lockIn(square, inSquare, line, inLine)
     return input
 }
 
 // For any value which appears in exactly one cell in a set, remove all other values from that cell
 func IsolateSingletons(board Set) Set {
-    singletons := make(Cell, len(board) + 1)
-    for i := range singletons {
-        singletons[i] = -1
+    once, more := C(), C()
+    for _, cell := range board {
+        more |= once & cell
+        once |= cell
     }
-    for i := range board {
-        for _, v := range board[i] {
-            if singletons[v] == -1 {
-                singletons[v] = i
-            } else {
-                singletons[v] = -2
-            }
+    singletons := once.difference(more)
+
+    for i, cell := range board {
+        if found := cell & singletons; found != 0 {
+            // Should two values only appear here, keep the larger.
+            board[i] = Cell(1) << uint(63 - bits.LeadingZeros64(uint64(found)))
         }
     }
 
-    for i := range singletons {
-        if singletons[i] > -1 {
-            board[singletons[i]] = make(Cell, 1)
-            board[singletons[i]][0] = i
+    return board
+}
+
+// For any size cells which between them can only hold size values, take
+// those values out of the set's other cells: naked pairs, triples and
+// quads, where ConstrainSet does naked singles.
+func ConstrainSubsets(size int) func(Set) Set {
+    return func(set Set) Set {
+        for _, sub := range set.nakedSubsets(size) {
+            for i := range set {
+                if !sub.has(i) {
+                    set[i] = set[i].difference(sub.values)
+                }
+            }
         }
+        return set
     }
+}
 
-    return board
+// For any size values which between them can only go in size cells, take
+// every other value out of those cells: hidden pairs, triples and quads,
+// where IsolateSingletons does hidden singles.
+func IsolateSubsets(size int) func(Set) Set {
+    return func(set Set) Set {
+        for _, sub := range set.hiddenSubsets(size) {
+            for _, i := range sub.cells {
+                set[i] &= sub.values
+            }
+        }
+        return set
+    }
 }
 
 // Fill out all possible values in a cell
 func Normalize(max int, cell *Cell) {
-    for j := 0; j < max; j++ {
-        (*cell)[j] = j + 1
-    }
+    *cell = Cell(1) << uint(max) - 1
 }
 
 func Create(max int) Cell {
-    cell := make(Cell, max)
+    cell := C()
     Normalize(max, &cell)
     return cell
 }
 
 func Copy(boardCell Cell) Cell {
-    iLen := len(boardCell)
-    cell := make(Cell, iLen)
-    for j := 0; j < iLen; j++ {
-        cell[j] = boardCell[j]
-    }
-    return cell
+    return boardCell
 }
 
 // Populate all cells with the '0' value with a full range of possible values
@@ -132,7 +150,7 @@ func NormalizeBoard(board Set) Set {
     max := len(board)
     outBoard := make(Set, max)
     for i := range board {
-        if len(board[i]) == 0 {
+        if board[i].isEmpty() {
             outBoard[i] = Create(max)
         } else {
             outBoard[i] = Copy(board[i])
@@ -155,65 +173,65 @@ func columnsOf(board Board) Board {
     return output
 }
 
-// Map each sub-square of the board to a row in the output.
+// Map each square of the board's standard geometry to a row in the
+// output, its cells in reading order.
 func squaresOf(board Board) Board {
     output := make(Board, len(board))
     for i := range output {
         output[i] = make(Set, len(board))
     }
-    coords := coordsMapForBoardOfLength(len(board))
-    for i := range board {
-        for j := range board[i] {
-            outI, outJ := coords(i, j)
-            if len(output) <= outI || len(output[outI]) <= outJ {
-                fmt.Printf("Failed at output[%v][%v]: %v, %v\n", outI, outJ, i, j)
+    for _, u := range StandardGeometry(len(board)).Units {
+        if u.Kind == Square {
+            for i, p := range u.Cells {
+                output[u.Index][i] = board[p.Row][p.Col]
             }
-            output[outI][outJ] = board[i][j]
         }
     }
     return output
 }
 
+// The shape of the boxes on a length-by-length board: as near square as
+// can be, and wider than they are tall, so 2x3 for 6 and 3x4 for 12.
+// Returns 0, 0 for lengths, like primes, which can't be split into boxes.
+func boxShape(length int) (int, int) {
+    for rows := int(math.Sqrt(float64(length))); rows > 1; rows-- {
+        if length % rows == 0 {
+            return rows, length / rows
+        }
+    }
+    if length == 1 {
+        return 1, 1
+    }
+    return 0, 0
+}
+
+// The number of rows and columns in each of the board's boxes, or 0, 0
+// if it has none. A board only knows its size, so these are the boxes
+// boxShape gives for it, as Step, GoString and StandardGeometry use; boxes
+// of any other shape, like the 3x2 ones of NewGeometry(Boxes(3, 2)), need
+// a Geometry.
+func (input Board) BoxSize() (int, int) {
+    return boxShape(len(input))
+}
+
 // Generate the coordinates map function to support the squaresOf() function.
 func coordsMapForBoardOfLength(length int) (func(int,int) (int,int)) {
-    fLen := float64(length)
-    squareSize := int(math.Floor(math.Sqrt(fLen)))
-    if squareSize * squareSize != length {
+    rows, cols := boxShape(length)
+    if rows == 0 {
         return func(i,j int) (int,int) {
             return 0,0
         }
     }
     return func(i, j int) (int, int) {
-        return ((i/squareSize)*squareSize + j/squareSize), (j%squareSize + (i%squareSize)*squareSize)
+        return ((i/rows)*rows + j/cols), (j%cols + (i%rows)*cols)
     }
 }
 
-
-func (board Board) Step(filter func(Set) Set) (Board) {
-    for i := range board {
-        board[i] = filter(board[i])
-    }
-
-    cols := columnsOf(board)
-    for i, col := range cols {
-        updatedCol := filter(col)
-        for j := range updatedCol {
-            board[j][i] = updatedCol[j]
-        }
-    }
-
-    squares := squaresOf(board)
-    for i, square := range squares {
-        squares[i] = filter(square)
-    }
-    mapper := coordsMapForBoardOfLength(len(board))
-    for i := range board {
-        for j := range board[i] {
-            squareI, squareJ := mapper(i, j)
-            board[i][j] = squares[squareI][squareJ]
-        }
-    }
-    return board
+// Apply the filter to every row, column and square in turn, returning the
+// result, as the board's StandardGeometry steps it. The board itself is
+// left alone, so boards can be stepped from many goroutines at once.
+func (input Board) Step(filter func(Set) Set) (Board) {
+    return StandardGeometry(len(input)).Step(input, filter)
 }
 
 
@@ -229,10 +247,9 @@ func ConstrainSet(set Set) Set {
 
     missingValue := make(Set, len(set))
     for i, cell := range set {
-        missingValue[i] = Cell{}
-        if len(cell) == 0 {
+        if cell.isEmpty() {
             missingValue[i] = notFound
-        } else if len(cell) != 1 {
+        } else if !cell.IsSolved() {
             missingValue[i] = cell.intersection(notFound)
         } else {
             missingValue[i] = cell
@@ -244,7 +261,7 @@ func ConstrainSet(set Set) Set {
 
 func (input Board) DebugString() string {
     out := ""
-    maxWidths := make(Cell, len(input))
+    maxWidths := make([]int, len(input))
     for _, row := range input {
         for col, cell := range row {
             cellWidth := len(fmt.Sprintf("%v", cell))
@@ -265,22 +282,77 @@ func (input Board) DebugString() string {
     return out
 }
 
+// The board drawn with its BoxSize boxes; a Geometry's Draw draws those
+// of any other shape.
 func (input Board) GoString() string {
+    return Digits.Draw(input, nil)
+}
+
+// Draw the board written with the alphabet, with the geometry's boxes, or
+// those Board.BoxSize gives for an ordinary board, marked out. If the
+// geometry has diagonals or shaded cells, every cell gets an extra column:
+// '*' for those on a diagonal, 'e' or 'o' for those shaded even or odd, or
+// a space. A sign between two cells takes the place of the space or '|'
+// after the left one, or goes under the top one, on the line of dashes or
+// a line of its own. geo may be nil for an ordinary board.
+func (a Alphabet) Draw(input Board, geo *Geometry) string {
+    marked := map[Pos]rune{}
+    for p := range geo.diagonalCells() {
+        marked[p] = '*'
+    }
+    notes := geo.annotations()
+    for _, p := range notes.Even {
+        marked[p] = 'e'
+    }
+    for _, p := range notes.Odd {
+        marked[p] = 'o'
+    }
+    across, down := notes.signs()
+    region := geo.regionOf(len(input))
+    // Whether q is off the board or in another box than p.
+    edge := func(p, q Pos) bool {
+        return q.Row == len(input) || q.Col == len(input) || region(p) != region(q)
+    }
+    width := 2
+    if len(marked) > 0 {
+        width = 3
+    }
     out := ""
-    for row, cols := range input {
-        for col, cell := range cols {
-            sep := " "
-            if col % 3 == 2 {
-                sep = "|"
+    for row, cells := range input {
+        under := []rune(strings.Repeat(" ", width * len(input)))
+        ruled, signed := false, false
+        for col := range cells {
+            if edge(Pos{row, col}, Pos{row + 1, col}) {
+                copy(under[width * col:], []rune(strings.Repeat("-", width)))
+                ruled = true
+            }
+        }
+        for col, cell := range cells {
+            p := Pos{row, col}
+            if m, ok := marked[p]; ok {
+                out += string(m)
+            } else if width == 3 {
+                out += " "
+            }
+            sep := ' '
+            if edge(p, Pos{row, col + 1}) {
+                sep = '|'
+            }
+            if sign, ok := across[p]; ok {
+                sep = sign
             }
-            if len(cell) == 1 {
-                out += fmt.Sprintf("%d%s", cell[0], sep)
+            if sign, ok := down[p]; ok {
+                under[width * col + width - 2] = sign
+                signed = true
+            }
+            if cell.IsSolved() {
+                out += fmt.Sprintf("%c%c", a.symbolFor(cell.Value()), sep)
             } else {
-                out += fmt.Sprintf(" %s", sep)
+                out += fmt.Sprintf(" %c", sep)
             }
         }
-        if row % 3 == 2 {
-            out += fmt.Sprintf("\n------------------\n")
+        if ruled || signed {
+            out += fmt.Sprintf("\n%s\n", strings.TrimRight(string(under), " "))
         } else {
             out += "\n"
         }
@@ -299,11 +371,54 @@ func (input Board) IsSolved() bool {
     return true
 }
 
+// Solve the board, falling back to a depth-first search when constraint
+// propagation stops making progress. Returns nil if there is no solution.
 func (input Board) Solve() (Board) {
-    input.Step(ConstrainSet)
-    for !input.IsSolved() {
-        input = input.Step(ConstrainSet)
-        fmt.Printf("Board: \n%#v\n", input)
-    }
-    return input
+    var solution Board
+    defaultSolver.each(context.Background(), input, nil, func(b Board) bool {
+        solution = b
+        return false
+    })
+    return solution
+}
+
+// Solve the board, reporting why when it can't be. Errors are a
+// *ContradictionError when the givens or what follows from them break a
+// row, column or square; ErrNoProgress when the puzzle has no solution for
+// a less direct reason; ErrMultipleSolutions, alongside one of the
+// solutions, when the puzzle isn't unique; or the context's error if it is
+// done first. The context is checked between every step.
+func (input Board) SolveE(ctx context.Context) (Board, error) {
+    return defaultSolver.Solve(ctx, input)
+}
+
+// Count the board's solutions, giving up once limit have been found; a
+// limit of 2 is enough to tell whether a puzzle is unique. A limit of 0 or
+// less counts them all.
+func (input Board) CountSolutions(limit int) int {
+    count := 0
+    defaultSolver.each(context.Background(), input, nil, func(Board) bool {
+        count++
+        return limit <= 0 || count < limit
+    })
+    return count
+}
+
+// Send every solution of the board down the returned channel, which is
+// closed once they have all been found or the context is done.
+func (input Board) Solutions(ctx context.Context) <-chan Board {
+    out := make(chan Board)
+    board := input.clone()
+    go func() {
+        defer close(out)
+        defaultSolver.each(ctx, board, nil, func(b Board) bool {
+            select {
+                case out <- b:
+                    return true
+                case <-ctx.Done():
+                    return false
+            }
+        })
+    }()
+    return out
 }
@@ -3,17 +3,16 @@ package sudoku
 import (
     matchers "github.com/tychofreeman/go-matchers"
     "testing"
+    "context"
+    "errors"
     "fmt"
 )
 
 func containsData(container Board, containee Set) bool {
     Row: for i := range container {
         for j := range container[i] {
-            for k := range container[i][j] {
-                if len(containee) <= j || len(containee[j]) <= k || containee[j][k] != container[i][j][k] {
-                    continue Row
-
-                }
+            if len(containee) <= j || containee[j] & container[i][j] != container[i][j] {
+                continue Row
             }
         }
         return true
@@ -30,28 +29,13 @@ func validateSameCells(t *testing.T, expected Board, rows Board) {
     }
 }
 
-func IsExactly(candidate []int, value []int) bool {
-    if len(candidate) == len(value) {
-        for i := range candidate {
-            if candidate[i] != value[i] {
-                return false
-            }
-        }
-    } else {
-        return false
-    }
-    return true
+func IsExactly(candidate Cell, value Cell) bool {
+    return candidate == value
 }
 
-func HasAllOf(candidate []int, values []int) bool {
+func HasAllOf(candidate Cell, values []int) bool {
     for _, value := range values {
-        found := false
-        for _, candidateValue := range candidate {
-            if candidateValue == value {
-                found = true
-            }
-        }
-        if !found {
+        if !candidate.Has(value) {
             return false
         }
     }
@@ -65,10 +49,8 @@ func TestNormalizeEmptyBoard(t *testing.T) {
     actual := NormalizeBoard(input)
 
     for i := range actual {
-        for j := range actual[i] {
-            if actual[i][j] != expected[i][j] {
-                t.Errorf("At %v,%v actual %v but expected %v", i, j, actual[i][j], expected[i][j])
-            }
+        if actual[i] != expected[i] {
+            t.Errorf("At %v actual %v but expected %v", i, actual[i], expected[i])
         }
     }
 }
@@ -188,12 +170,34 @@ func TestIsolatesANumberWhichOnlyAppearsOnceAndDoesNotFallForStupidTricks(t *tes
     }
 }
 
+func TestConstrainsNakedSubsets(t *testing.T) {
+    input := Set{C(1,2), C(5,6), C(2,3), C(1,3), C(1,2,3,4), C(3,4,5,6)}
+    result := ConstrainSubsets(3)(input)
+    expected := Set{C(1,2), C(5,6), C(2,3), C(1,3), C(4), C(4,5,6)}
+    for i := range expected {
+        if !IsExactly(result[i], expected[i]) {
+            t.Errorf("Expected %v in cell %d, but got %v", expected[i], i, result[i])
+        }
+    }
+}
+
+func TestIsolatesHiddenSubsets(t *testing.T) {
+    input := Set{C(1,2,5), C(3,4,5,6), C(1,2,6), C(3,4), C(3,4,5,6), C(3,4,5,6)}
+    result := IsolateSubsets(2)(input)
+    expected := Set{C(1,2), C(3,4,5,6), C(1,2), C(3,4), C(3,4,5,6), C(3,4,5,6)}
+    for i := range expected {
+        if !IsExactly(result[i], expected[i]) {
+            t.Errorf("Expected %v in cell %d, but got %v", expected[i], i, result[i])
+        }
+    }
+}
+
 func TestDegenerateCoords3By3MapTo1By1Squares(t *testing.T) {
-    data := Set{
-        C(0,0,0,0),
-        C(0,1,0,0),
-        C(1,0,0,0),
-        C(2,2,0,0),
+    data := [][]int{
+        {0,0,0,0},
+        {0,1,0,0},
+        {1,0,0,0},
+        {2,2,0,0},
     }
 
     f := coordsMapForBoardOfLength(3)
@@ -206,22 +210,22 @@ func TestDegenerateCoords3By3MapTo1By1Squares(t *testing.T) {
 }
 
 func TestCoords9By9MapTo3By3Squares(t *testing.T) {
-    data := Set{
-        C(0,0,0,0),
-        C(1,0,0,3),
-        C(1,1,0,4),
-        C(2,2,0,8),
-        C(0,6,2,0),
-        C(0,7,2,1),
-        C(0,8,2,2),
-        C(1,6,2,3),
-        C(2,6,2,6),
-        C(3,0,3,0),
-        C(3,2,3,2),
-        C(4,0,3,3),
-        C(3,6,5,0),
-        C(4,6,5,3),
-        C(5,8,5,8),
+    data := [][]int{
+        {0,0,0,0},
+        {1,0,0,3},
+        {1,1,0,4},
+        {2,2,0,8},
+        {0,6,2,0},
+        {0,7,2,1},
+        {0,8,2,2},
+        {1,6,2,3},
+        {2,6,2,6},
+        {3,0,3,0},
+        {3,2,3,2},
+        {4,0,3,3},
+        {3,6,5,0},
+        {4,6,5,3},
+        {5,8,5,8},
     }
 
     f := coordsMapForBoardOfLength(9)
@@ -233,6 +237,57 @@ func TestCoords9By9MapTo3By3Squares(t *testing.T) {
     }
 }
 
+func TestCoords6By6MapTo2By3Boxes(t *testing.T) {
+    data := [][]int{
+        {0,0,0,0},
+        {0,2,0,2},
+        {0,3,1,0},
+        {1,0,0,3},
+        {1,5,1,5},
+        {2,0,2,0},
+        {3,4,3,4},
+        {5,5,5,5},
+    }
+
+    f := coordsMapForBoardOfLength(6)
+    for _, datum := range data {
+        i, j := f(datum[0], datum[1])
+        if i != datum[2] || j != datum[3] {
+            t.Errorf("For input %v,%v, expected %v,%v but got %v,%v\n", datum[0],datum[1], datum[2], datum[3], i, j)
+        }
+    }
+}
+
+func TestBoxSizesAreAsSquareAsTheyCanBe(t *testing.T) {
+    data := [][]int{
+        {4,2,2},
+        {6,2,3},
+        {8,2,4},
+        {9,3,3},
+        {10,2,5},
+        {12,3,4},
+        {16,4,4},
+        {7,0,0},
+    }
+
+    for _, datum := range data {
+        rows, cols := NewBoard(datum[0]).BoxSize()
+        if rows != datum[1] || cols != datum[2] {
+            t.Errorf("For %v, expected %vx%v boxes but got %vx%v", datum[0], datum[1], datum[2], rows, cols)
+        }
+    }
+}
+
+func TestGoStringSeparatesTheBoxes(t *testing.T) {
+    board, _ := Parse("123456" + "456123" + "214365" + "365214" + "531642" + "642531")
+    expected := "1 2 3|4 5 6|\n4 5 6|1 2 3|\n------------\n" +
+        "2 1 4|3 6 5|\n3 6 5|2 1 4|\n------------\n" +
+        "5 3 1|6 4 2|\n6 4 2|5 3 1|\n------------\n"
+    if board.GoString() != expected {
+        t.Errorf("Expected\n%v\nbut got\n%v", expected, board.GoString())
+    }
+}
+
 func _TestSauaresOf81CellBoardAre3X3Nondrants(t *testing.T) {
     input := Board{
         Set{C(1),C(1),C(1),C(2),C(2),C(2),C(2),C(2),C(2)},
@@ -411,7 +466,7 @@ func TestSolvesThis(t *testing.T) {
     matchers.AssertThat(t, output, matchers.Equals(expected))
 }
 
-func DISABLED_TestSolvesExtremePuzzle(t *testing.T) {
+func TestSolvesExtremePuzzle(t *testing.T) {
     input := Board{
         Set{C( ),C( ),C(5),C(6),C( ),C( ),C( ),C( ),C(7)},
         Set{C( ),C(6),C( ),C( ),C(4),C( ),C( ),C(8),C( )},
@@ -429,22 +484,187 @@ func DISABLED_TestSolvesExtremePuzzle(t *testing.T) {
     matchers.AssertThat(t, output.IsSolved(), matchers.IsTrue)
 }
 
+func TestSolveReturnsNilWhenThereIsNoSolution(t *testing.T) {
+    input := Board{
+        Set{C(5),C( ),C( ),C( ),C(5),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( ),C( )},
+    }
+
+    if output := input.Solve(); output != nil {
+        t.Errorf("Expected no solution, but got:\n%#v", output)
+    }
+}
+
+func TestSolveDoesNotChangeItsInput(t *testing.T) {
+    input := Board{
+        Set{C( ),C(2)},
+        Set{C( ),C( )},
+    }
+
+    input.Solve()
+
+    if !input[0][0].isEmpty() || !input[1][1].isEmpty() {
+        t.Errorf("Solve() should not modify the board it was given, but it is now %v", input)
+    }
+}
+
+func TestSolveEReportsWhereTheContradictionIs(t *testing.T) {
+    input := Board{
+        Set{C( ),C( ),C( ),C( )},
+        Set{C(3),C( ),C( ),C(3)},
+        Set{C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( )},
+    }
+
+    _, err := input.SolveE(context.Background())
+
+    if !errors.Is(err, ErrContradiction) {
+        t.Fatalf("Expected a contradiction, but got %v", err)
+    }
+    contradiction := err.(*ContradictionError)
+    if contradiction.Unit != Row || contradiction.Index != 1 || contradiction.Value != 3 {
+        t.Errorf("Expected the contradiction to be 3 in row 2, but got %v", err)
+    }
+}
+
+func TestSolveEReportsMultipleSolutions(t *testing.T) {
+    input := Board{
+        Set{C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( )},
+        Set{C( ),C( ),C( ),C( )},
+    }
+
+    output, err := input.SolveE(context.Background())
+
+    if err != ErrMultipleSolutions {
+        t.Errorf("Expected ErrMultipleSolutions, but got %v", err)
+    }
+    matchers.AssertThat(t, output.IsSolved(), matchers.IsTrue)
+}
+
+func TestSolveEReportsPuzzlesWithNoSolution(t *testing.T) {
+    // The extreme puzzle below, with an 8 in the top left corner.
+    input := Board{
+        Set{C(8),C( ),C(5),C(6),C( ),C( ),C( ),C( ),C(7)},
+        Set{C( ),C(6),C( ),C( ),C(4),C( ),C( ),C(8),C( )},
+        Set{C( ),C( ),C(9),C( ),C( ),C( ),C( ),C( ),C(1)},
+        Set{C(7),C( ),C( ),C( ),C( ),C( ),C(1),C( ),C( )},
+        Set{C( ),C(8),C( ),C( ),C(1),C( ),C( ),C(2),C( )},
+        Set{C( ),C( ),C(2),C( ),C( ),C( ),C( ),C( ),C(4)},
+        Set{C(5),C( ),C( ),C( ),C( ),C( ),C(3),C( ),C( )},
+        Set{C( ),C(2),C( ),C( ),C(9),C( ),C( ),C(6),C( )},
+        Set{C(4),C( ),C( ),C( ),C( ),C(7),C(5),C( ),C( )},
+    }
+
+    if _, err := input.SolveE(context.Background()); err != ErrNoProgress {
+        t.Errorf("Expected ErrNoProgress, but got %v", err)
+    }
+}
+
+func TestSolveEStopsWhenTheContextIsDone(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    if _, err := unsolved.clone().SolveE(ctx); err != context.Canceled {
+        t.Errorf("Expected context.Canceled, but got %v", err)
+    }
+}
+
+func TestCountSolutions(t *testing.T) {
+    if count := unsolved.CountSolutions(2); count != 1 {
+        t.Errorf("Expected a unique solution, but found %v", count)
+    }
+    if count := NewBoard(4).CountSolutions(0); count != 288 {
+        t.Errorf("Expected an empty 4x4 board to have 288 solutions, but found %v", count)
+    }
+    if count := NewBoard(4).CountSolutions(2); count != 2 {
+        t.Errorf("Expected counting to stop at the limit of 2, but found %v", count)
+    }
+}
+
+func TestSolutionsSendsEverySolution(t *testing.T) {
+    count := 0
+    for solution := range NewBoard(4).Solutions(context.Background()) {
+        if !solution.IsSolved() {
+            t.Errorf("Expected only solved boards, but got\n%v", solution.DebugString())
+        }
+        count++
+    }
+    if count != 288 {
+        t.Errorf("Expected 288 solutions, but got %v", count)
+    }
+}
+
+func TestSolutionsStopsWhenTheContextIsDone(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    solutions := NewBoard(9).Solutions(ctx)
+    <-solutions
+    cancel()
+    for range solutions {
+    }
+}
+
 func TestRemoves1sFromRestOfSquareWhenASubsetMustContainThem(t *testing.T) {
     input := []Set{
         Set{C(1,2),C(1,3),C(1,4),C(1,4)},
         Set{C(1,4),C(1,4),C(2  ),C(3  )},
     }
-    intersect := []int{2,3}
+    // As on a 4x4 board, where the top left square's last two cells are
+    // the first two of the second row.
+    inSquare, inLine := []int{2,3}, []int{0,1}
     expected := []Set{
         Set{C(2  ),C(3  ),C(1,4),C(1,4)},
         Set{C(1,4),C(1,4),C(2  ),C(3  )},
     }
 
-    output := ConstrainLinearAndSquare(input, intersect)
+    output := ConstrainLinearAndSquare(input, inSquare, inLine)
 
     matchers.AssertThat(t, output, matchers.Equals(expected))
 }
 
+func TestRemovesValuesFromRestOfLineWhenTheSquareHasThemInIt(t *testing.T) {
+    input := []Set{
+        Set{C(1,4),C(2,3),C(1,4),C(2,3)},
+        Set{C(1,4),C(1,4),C(1,2,4),C(3,4)},
+    }
+    // The first square of a 4x4 board and its first column.
+    inSquare, inLine := []int{0,2}, []int{0,1}
+    expected := []Set{
+        Set{C(1,4),C(2,3),C(1,4),C(2,3)},
+        Set{C(1,4),C(1,4),C(2    ),C(3  )},
+    }
+
+    output := ConstrainLinearAndSquare(input, inSquare, inLine)
+
+    matchers.AssertThat(t, output, matchers.Equals(expected))
+}
+
+func TestZeroOffsetComplementOf(t *testing.T) {
+    data := []struct {
+        orig     []int
+        max      int
+        expected []int
+    }{
+        {[]int{}, 3, []int{0, 1, 2}},
+        {[]int{1}, 3, []int{0, 2}},
+        {[]int{2, 3}, 4, []int{0, 1}},
+        {[]int{3, 0, 2}, 5, []int{1, 4}},
+    }
+    for _, datum := range data {
+        if out := zeroOffsetComplementOf(datum.orig, datum.max); fmt.Sprint(out) != fmt.Sprint(datum.expected) {
+            t.Errorf("Expected %v for %v up to %d, but got %v", datum.expected, datum.orig, datum.max, out)
+        }
+    }
+}
+
 type CellSet Set
 func (cs CellSet) Equals(other interface{}) (b bool, s string) {
     b = true
@@ -453,25 +673,7 @@ func (cs CellSet) Equals(other interface{}) (b bool, s string) {
     switch o := other.(type) {
     case Set:
         for i := range cs {
-            found := true
-            if len(cs[i]) != len(o[i]) {
-                found = false
-            } else {
-                for j := range cs[i] {
-                    inOther := false
-                    for _, otherValue := range o[i] {
-                        if otherValue == cs[i][j] {
-                            inOther = true
-                            break
-                        }
-                    }
-                    if !inOther {
-                        found = false
-                        break
-                    }
-                }
-            }
-            if !found {
+            if !cs[i].Equals(o[i]) {
                 b = false
                 s += fmt.Sprintf("Differ on line %d - %v vs %v", i, cs[i], o[i])
             }
@@ -485,3 +687,21 @@ func (cs CellSet) Equals(other interface{}) (b bool, s string) {
 }
 
 
+
+func TestSolves25By25Boards(t *testing.T) {
+    puzzle, err := Generate(GenerateOptions{Size: 25, Seed: 1, Clues: 340})
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    board, err := Letters.Parse(Letters.Format(puzzle))
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    solution, err := board.SolveE(context.Background())
+    if err != nil {
+        t.Fatalf("Unexpected error %v", err)
+    }
+    if _, err := NewGrid(solution); err != nil || !solution.IsSolved() {
+        t.Errorf("Expected a solution, but got\n%s", Letters.Draw(solution, nil))
+    }
+}
@@ -0,0 +1,559 @@
+package sudoku
+
+import (
+    "math/bits"
+)
+
+// A built-in Strategy: find adds whatever it can deduce from the grid to
+// the result.
+type technique struct {
+    name       string
+    difficulty float64
+    find       func(g *Grid, r *Result)
+}
+
+func (t technique) Name() string {
+    return t.name
+}
+
+func (t technique) Difficulty() float64 {
+    return t.difficulty
+}
+
+// Deductions are named for the technique, unless find names them more
+// closely, as a finned fish does when it is sashimi.
+func (t technique) Apply(g *Grid) Result {
+    var r Result
+    t.find(g, &r)
+    for i := range r.Deductions {
+        if r.Deductions[i].Technique == "" {
+            r.Deductions[i].Technique = t.name
+        }
+    }
+    return r
+}
+
+// Eliminations of the values from each of the cells.
+func eliminations(values Cell, cells ...Pos) []Elimination {
+    out := make([]Elimination, len(cells))
+    for i, p := range cells {
+        out[i] = Elimination{p, values}
+    }
+    return out
+}
+
+var (
+    // A value which can only go in one cell of a unit goes there.
+    HiddenSingle = UnitFilter("Hidden single", 1.5, IsolateSingletons)
+    // A solved cell's value can't go anywhere else in its units.
+    NakedSingle = UnitFilter("Naked single", 2.3, ConstrainSet)
+    // A value confined to one row or column of a square can't go elsewhere in that line.
+    Pointing Strategy = technique{"Pointing", 2.6, pointing}
+    // A value confined to one square within a row or column can't go elsewhere in that square.
+    Claiming Strategy = technique{"Claiming", 2.8, claiming}
+    // Pointing and claiming together: box-line reduction between every
+    // square and every line crossing it, in both directions.
+    LockedCandidates Strategy = technique{"Locked candidates", 2.6, lockedCandidatesIn}
+    NakedPair Strategy = technique{"Naked pair", 3.0, nakedSubsets(2)}
+    XWing Strategy = technique{"X-Wing", 3.2, fish(2, false)}
+    HiddenPair Strategy = technique{"Hidden pair", 3.4, hiddenSubsets(2)}
+    NakedTriple Strategy = technique{"Naked triple", 3.6, nakedSubsets(3)}
+    Swordfish Strategy = technique{"Swordfish", 3.8, fish(3, false)}
+    HiddenTriple Strategy = technique{"Hidden triple", 4.0, hiddenSubsets(3)}
+    XYWing Strategy = technique{"XY-Wing", 4.2, xyWing}
+    // An X-Wing but for fins, the places in its base rows or columns
+    // outside its cover; sashimi if the fins leave a base line one place.
+    FinnedXWing Strategy = technique{"Finned X-Wing", 4.3, fish(2, true)}
+    XYZWing Strategy = technique{"XYZ-Wing", 4.4, xyzWing}
+    FinnedSwordfish Strategy = technique{"Finned Swordfish", 4.6, fish(3, true)}
+    NakedQuad Strategy = technique{"Naked quad", 5.0, nakedSubsets(4)}
+    Jellyfish Strategy = technique{"Jellyfish", 5.2, fish(4, false)}
+    HiddenQuad Strategy = technique{"Hidden quad", 5.4, hiddenSubsets(4)}
+    FinnedJellyfish Strategy = technique{"Finned Jellyfish", 5.6, fish(4, true)}
+    SimpleColouring Strategy = technique{"Simple colouring", 6.6, simpleColouring}
+)
+
+// Every built-in strategy, easiest first.
+var Strategies = []Strategy{
+    HiddenSingle,
+    NakedSingle,
+    Pointing,
+    Claiming,
+    NakedPair,
+    XWing,
+    HiddenPair,
+    NakedTriple,
+    Swordfish,
+    HiddenTriple,
+    XYWing,
+    FinnedXWing,
+    XYZWing,
+    FinnedSwordfish,
+    NakedQuad,
+    Jellyfish,
+    HiddenQuad,
+    FinnedJellyfish,
+    SimpleColouring,
+}
+
+// Call visit with every way of picking k of 0..n-1, each in increasing
+// order, until it returns false.
+func combinations(n, k int, visit func([]int) bool) {
+    pick := make([]int, k)
+    var choose func(start, depth int) bool
+    choose = func(start, depth int) bool {
+        if depth == k {
+            return visit(pick)
+        }
+        for i := start; i <= n - (k - depth); i++ {
+            pick[depth] = i
+            if !choose(i + 1, depth + 1) {
+                return false
+            }
+        }
+        return true
+    }
+    choose(0, 0)
+}
+
+// The cells of a unit which could hold v.
+func (g *Grid) where(u Unit, v int) []Pos {
+    places := []Pos{}
+    for _, p := range u.Cells {
+        if g.At(p).Has(v) {
+            places = append(places, p)
+        }
+    }
+    return places
+}
+
+// If all the places v can go in the from'th unit are also in the to'th,
+// then v can't go anywhere else in the to'th.
+func lockedCandidates(g *Grid, r *Result, from, to int) {
+    // Whether p is in the i'th unit.
+    in := func(p Pos, i int) bool {
+        for _, j := range g.unitsAt[p.Row][p.Col] {
+            if j == i {
+                return true
+            }
+        }
+        return false
+    }
+    var shared, fromOnly, toOnly Cell
+    for _, p := range g.Units[from].Cells {
+        if in(p, to) {
+            shared |= g.At(p)
+        } else {
+            fromOnly |= g.At(p)
+        }
+    }
+    for _, p := range g.Units[to].Cells {
+        if !in(p, from) {
+            toOnly |= g.At(p)
+        }
+    }
+    // Only look for the places of values which have some to go.
+    for _, v := range (shared.difference(fromOnly) & toOnly).Values() {
+        d := Deduction{Unit: &g.Units[from], Cells: g.where(g.Units[from], v), Values: C(v)}
+        for _, p := range g.Units[to].Cells {
+            if !in(p, from) {
+                d.Eliminations = append(d.Eliminations, Elimination{p, C(v)})
+            }
+        }
+        r.add(g, d)
+    }
+}
+
+// Run lockedCandidates for every square and the rows and columns crossing it.
+func boxLine(g *Grid, r *Result, fromSquare bool) {
+    crossing := make([]bool, len(g.Units))
+    for square := range g.Units {
+        if g.Units[square].Kind != Square {
+            continue
+        }
+        for j := range crossing {
+            crossing[j] = false
+        }
+        for _, p := range g.Units[square].Cells {
+            for _, j := range g.unitsAt[p.Row][p.Col] {
+                crossing[j] = g.Units[j].Kind != Square
+            }
+        }
+        for line := range g.Units {
+            if !crossing[line] {
+                continue
+            }
+            if fromSquare {
+                lockedCandidates(g, r, square, line)
+            } else {
+                lockedCandidates(g, r, line, square)
+            }
+        }
+    }
+}
+
+func pointing(g *Grid, r *Result) {
+    boxLine(g, r, true)
+}
+
+func claiming(g *Grid, r *Result) {
+    boxLine(g, r, false)
+}
+
+func lockedCandidatesIn(g *Grid, r *Result) {
+    boxLine(g, r, true)
+    boxLine(g, r, false)
+}
+
+// size cells of a unit holding only size candidates between them: those
+// candidates can't go anywhere else in the unit.
+func nakedSubsets(size int) func(*Grid, *Result) {
+    return func(g *Grid, r *Result) {
+        var set Set
+        for k := range g.Units {
+            u := &g.Units[k]
+            set = g.setInto(set, *u)
+            for _, sub := range set.nakedSubsets(size) {
+                d := Deduction{Unit: u, Values: sub.values}
+                for i, p := range u.Cells {
+                    if sub.has(i) {
+                        d.Cells = append(d.Cells, p)
+                    } else {
+                        d.Eliminations = append(d.Eliminations, Elimination{p, sub.values})
+                    }
+                }
+                r.add(g, d)
+            }
+        }
+    }
+}
+
+// size values which can only go in the same size cells of a unit: those
+// cells can't hold anything else.
+func hiddenSubsets(size int) func(*Grid, *Result) {
+    return func(g *Grid, r *Result) {
+        var set Set
+        for k := range g.Units {
+            u := &g.Units[k]
+            set = g.setInto(set, *u)
+            for _, sub := range set.hiddenSubsets(size) {
+                d := Deduction{Unit: u, Values: sub.values}
+                for _, i := range sub.cells {
+                    p := u.Cells[i]
+                    d.Cells = append(d.Cells, p)
+                    d.Eliminations = append(d.Eliminations, Elimination{p, set[i].difference(sub.values)})
+                }
+                r.add(g, d)
+            }
+        }
+    }
+}
+
+// The places each line of a view of the board, its rows or columnsOf
+// them, has for v, as a mask of positions along the line.
+func placesIn(lines Board, v int) []uint64 {
+    masks := make([]uint64, len(lines))
+    for line, cells := range lines {
+        for pos, cell := range cells {
+            if cell.Has(v) {
+                masks[line] |= 1 << uint(pos)
+            }
+        }
+    }
+    return masks
+}
+
+// The fish the size of each name takes.
+var fishNames = map[int]string{2: "X-Wing", 3: "Swordfish", 4: "Jellyfish"}
+
+// size base rows whose places for a value all lie in the same size cover
+// columns: the value can't go anywhere else in those columns. And the
+// same with rows and columns swapped. A finned fish has places in its
+// base outside the cover, its fins: either a fin holds the value or the
+// fish does, so only cells of the cover which see every fin lose it. It
+// is sashimi if some base line has only one place in the cover.
+func fish(size int, finned bool) func(*Grid, *Result) {
+    return func(g *Grid, r *Result) {
+        // Only whole rows and columns are sure to hold every value.
+        n := len(g.Board)
+        if n != g.values {
+            return
+        }
+        views := map[UnitKind]Board{Row: g.Board, Column: columnsOf(g.Board)}
+        for v := 1; v <= n; v++ {
+            for _, kind := range []UnitKind{Row, Column} {
+                other := Column
+                if kind == Column {
+                    other = Row
+                }
+                at := func(line, pos int) Pos {
+                    if kind == Row {
+                        return Pos{line, pos}
+                    }
+                    return Pos{pos, line}
+                }
+                lineOf := func(kind UnitKind, i int) Unit {
+                    u := Unit{kind, i, make([]Pos, n)}
+                    for j := range u.Cells {
+                        if kind == Row {
+                            u.Cells[j] = Pos{i, j}
+                        } else {
+                            u.Cells[j] = Pos{j, i}
+                        }
+                    }
+                    return u
+                }
+                masks := placesIn(views[kind], v)
+                bases := []int{}
+                for line, mask := range masks {
+                    if count := bits.OnesCount64(mask); count >= 2 && (finned || count <= size) {
+                        bases = append(bases, line)
+                    }
+                }
+                // Say what follows from the fish with these base lines and
+                // cover positions.
+                found := func(base []int, cover uint64) {
+                    inBase := uint64(0)
+                    fins := []Pos{}
+                    for _, line := range base {
+                        inBase |= 1 << uint(line)
+                        for rest := masks[line] &^ cover; rest != 0; rest &= rest - 1 {
+                            fins = append(fins, at(line, bits.TrailingZeros64(rest)))
+                        }
+                    }
+                    // Only the cells of the cover outside the base, and
+                    // which see every fin, lose v; most fish have none.
+                    d := Deduction{Technique: fishNames[size], Values: C(v), Fins: fins}
+                    for line := 0; line < n; line++ {
+                        if inBase & (1 << uint(line)) != 0 {
+                            continue
+                        }
+                        Targets: for rest := masks[line] & cover; rest != 0; rest &= rest - 1 {
+                            p := at(line, bits.TrailingZeros64(rest))
+                            for _, fin := range fins {
+                                if !g.Sees(fin, p) {
+                                    continue Targets
+                                }
+                            }
+                            d.Eliminations = append(d.Eliminations, Elimination{p, C(v)})
+                        }
+                    }
+                    if len(d.Eliminations) == 0 {
+                        return
+                    }
+
+                    if finned {
+                        d.Technique = "Finned " + fishNames[size]
+                    }
+                    for _, line := range base {
+                        d.Base = append(d.Base, lineOf(kind, line))
+                        if bits.OnesCount64(masks[line] & cover) == 1 {
+                            d.Technique = "Sashimi " + fishNames[size]
+                        }
+                        for rest := masks[line]; rest != 0; rest &= rest - 1 {
+                            d.Cells = append(d.Cells, at(line, bits.TrailingZeros64(rest)))
+                        }
+                    }
+                    for rest := cover; rest != 0; rest &= rest - 1 {
+                        d.Cover = append(d.Cover, lineOf(other, bits.TrailingZeros64(rest)))
+                    }
+                    r.add(g, d)
+                }
+                combinations(len(bases), size, func(pick []int) bool {
+                    base := make([]int, size)
+                    union, elsewhere := uint64(0), uint64(0)
+                    for i, j := range pick {
+                        base[i] = bases[j]
+                        union |= masks[bases[j]]
+                    }
+                    for line, mask := range masks {
+                        if !contains(base, line) {
+                            elsewhere |= mask
+                        }
+                    }
+                    positions := []int{}
+                    for pos := 0; pos < n; pos++ {
+                        if union & (1 << uint(pos)) != 0 {
+                            positions = append(positions, pos)
+                        }
+                    }
+                    switch {
+                        case !finned && len(positions) == size:
+                            found(base, union)
+                        case finned && len(positions) > size:
+                            combinations(len(positions), size, func(pick []int) bool {
+                                cover := uint64(0)
+                                for _, i := range pick {
+                                    cover |= 1 << uint(positions[i])
+                                }
+                                // Every base line needs a place in the cover,
+                                // and the cover a place outside the base.
+                                for _, line := range base {
+                                    if masks[line] & cover == 0 {
+                                        return true
+                                    }
+                                }
+                                if elsewhere & cover != 0 {
+                                    found(base, cover)
+                                }
+                                return true
+                            })
+                    }
+                    return true
+                })
+            }
+        }
+    }
+}
+
+func contains(list []int, x int) bool {
+    for _, y := range list {
+        if x == y {
+            return true
+        }
+    }
+    return false
+}
+
+// The cells with exactly n candidates.
+func (g *Grid) cellsWith(n int) []Pos {
+    cells := []Pos{}
+    for r := range g.Board {
+        for c, cell := range g.Board[r] {
+            if cell.Len() == n {
+                cells = append(cells, Pos{r, c})
+            }
+        }
+    }
+    return cells
+}
+
+// A pivot cell {x,y} seeing two pincers {x,z} and {y,z}: whichever the
+// pivot is, one of the pincers is z, so z can't go in any cell seeing both.
+func xyWing(g *Grid, r *Result) {
+    bivalue := g.cellsWith(2)
+    for _, pivot := range bivalue {
+        for _, a := range bivalue {
+            xz := g.At(a)
+            if a == pivot || !g.Sees(pivot, a) || (xz & g.At(pivot)).Len() != 1 {
+                continue
+            }
+            z := xz.difference(g.At(pivot))
+            yz := g.At(pivot).difference(xz).union(z)
+            for _, b := range bivalue {
+                if b != pivot && b != a && g.At(b) == yz && g.Sees(pivot, b) {
+                    r.add(g, Deduction{
+                        Cells: []Pos{pivot, a, b},
+                        Values: z,
+                        Eliminations: eliminations(z, g.seenByAll(a, b)...),
+                    })
+                }
+            }
+        }
+    }
+}
+
+// As xyWing, but with a pivot {x,y,z}, so only cells which also see the
+// pivot lose z.
+func xyzWing(g *Grid, r *Result) {
+    bivalue := g.cellsWith(2)
+    for _, pivot := range g.cellsWith(3) {
+        xyz := g.At(pivot)
+        for i, a := range bivalue {
+            if g.At(a).difference(xyz) != 0 || !g.Sees(pivot, a) {
+                continue
+            }
+            for _, b := range bivalue[i + 1:] {
+                z := g.At(a) & g.At(b)
+                if g.At(b).difference(xyz) == 0 && g.At(b) != g.At(a) && g.Sees(pivot, b) && z.IsSolved() {
+                    r.add(g, Deduction{
+                        Cells: []Pos{pivot, a, b},
+                        Values: z,
+                        Eliminations: eliminations(z, g.seenByAll(pivot, a, b)...),
+                    })
+                }
+            }
+        }
+    }
+}
+
+// Follow the chains of units where a value has only two places, colouring
+// those places alternately: one colour holds the value, the other doesn't.
+// A colour seen twice in a unit must be the one that doesn't, and a cell
+// which sees both colours can't hold the value.
+func simpleColouring(g *Grid, r *Result) {
+    for v := 1; v <= g.values; v++ {
+        links := make(map[Pos][]Pos)
+        for _, u := range g.Units {
+            if places := g.where(u, v); len(places) == 2 {
+                links[places[0]] = append(links[places[0]], places[1])
+                links[places[1]] = append(links[places[1]], places[0])
+            }
+        }
+
+        coloured := make(map[Pos]bool)
+        for row := range g.Board {
+            for col := range g.Board[row] {
+                start := Pos{row, col}
+                if coloured[start] || len(links[start]) == 0 {
+                    continue
+                }
+                colours := [2][]Pos{}
+                colour := map[Pos]int{start: 0}
+                queue := []Pos{start}
+                for len(queue) > 0 {
+                    p := queue[0]
+                    queue = queue[1:]
+                    coloured[p] = true
+                    colours[colour[p]] = append(colours[colour[p]], p)
+                    for _, next := range links[p] {
+                        if _, seen := colour[next]; !seen {
+                            colour[next] = 1 - colour[p]
+                            queue = append(queue, next)
+                        }
+                    }
+                }
+                colourWrap(g, r, v, colours)
+                colourTrap(g, r, v, colour, colours)
+            }
+        }
+    }
+}
+
+func colourWrap(g *Grid, r *Result, v int, colours [2][]Pos) {
+    for _, same := range colours {
+        for i, a := range same {
+            for _, b := range same[i + 1:] {
+                if g.Sees(a, b) {
+                    r.add(g, Deduction{Cells: same, Values: C(v), Eliminations: eliminations(C(v), same...)})
+                    return
+                }
+            }
+        }
+    }
+}
+
+func colourTrap(g *Grid, r *Result, v int, colour map[Pos]int, colours [2][]Pos) {
+    d := Deduction{Cells: append(append([]Pos{}, colours[0]...), colours[1]...), Values: C(v)}
+    for row := range g.Board {
+        for col, cell := range g.Board[row] {
+            p := Pos{row, col}
+            if _, inChain := colour[p]; inChain || !cell.Has(v) {
+                continue
+            }
+            if seesAny(g, p, colours[0]) && seesAny(g, p, colours[1]) {
+                d.Eliminations = append(d.Eliminations, Elimination{p, C(v)})
+            }
+        }
+    }
+    r.add(g, d)
+}
+
+func seesAny(g *Grid, p Pos, others []Pos) bool {
+    for _, other := range others {
+        if g.Sees(p, other) {
+            return true
+        }
+    }
+    return false
+}